@@ -0,0 +1,87 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterRelaySpec defines the desired state of ClusterRelay
+type ClusterRelaySpec struct {
+	// URL is the WebSocket relay URL
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^wss://.*`
+	URL string `json:"url"`
+
+	// PublicDomain is the domain applications are published under (e.g., "portal.gosuda.org")
+	// Derived from the URL host if omitted
+	// +optional
+	PublicDomain string `json:"publicDomain,omitempty"`
+
+	// Description is a human-readable description of the relay
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Region is the location of the relay, for information only
+	// +optional
+	Region string `json:"region,omitempty"`
+}
+
+// ClusterRelayStatus defines the observed state of ClusterRelay.
+type ClusterRelayStatus struct {
+	// ObservedGeneration is the last observed spec generation
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ExposureCount is the number of PortalExposes referencing this relay
+	// +optional
+	ExposureCount int32 `json:"exposureCount,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+
+// ClusterRelay is the Schema for the clusterrelays API
+type ClusterRelay struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	// spec defines the desired state of ClusterRelay
+	// +required
+	Spec ClusterRelaySpec `json:"spec"`
+
+	// status defines the observed state of ClusterRelay
+	// +optional
+	Status ClusterRelayStatus `json:"status,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterRelayList contains a list of ClusterRelay
+type ClusterRelayList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitzero"`
+	Items           []ClusterRelay `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterRelay{}, &ClusterRelayList{})
+}
@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -36,29 +37,93 @@ type AppSpec struct {
 	Service ServiceRef `json:"service"`
 }
 
+// EndpointSpec defines an additional endpoint published by the same tunnel Deployment
+type EndpointSpec struct {
+	// Name identifies the endpoint within the PortalExpose (e.g., "admin", "grpc")
+	// "app" is reserved for spec.app
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=50
+	// +kubebuilder:validation:XValidation:rule="self != 'app'",message="endpoint name 'app' is reserved for spec.app"
+	Name string `json:"name"`
+
+	// Subdomain is the name the endpoint is published under on every relay
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	Subdomain string `json:"subdomain"`
+
+	// Service references the Kubernetes Service the endpoint forwards to
+	// +kubebuilder:validation:Required
+	Service ServiceRef `json:"service"`
+}
+
 // ServiceRef references a Kubernetes Service
 type ServiceRef struct {
 	// Name is the Service name in the same namespace
 	// +kubebuilder:validation:Required
 	Name string `json:"name"`
 
-	// Port is the Service port number to expose
+	// Port is the Service port name or number to expose
+	// It is resolved against the Service's ports on every reconcile
 	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:Minimum=1
-	// +kubebuilder:validation:Maximum=65535
-	Port int32 `json:"port"`
+	// +kubebuilder:validation:XIntOrString
+	// +kubebuilder:validation:XValidation:rule="type(self) == string ? self != '' : (self >= 1 && self <= 65535)",message="port must be a port name or a number between 1 and 65535"
+	Port intstr.IntOrString `json:"port"`
 }
 
 // RelayTarget defines a Portal relay endpoint
+// Exactly one of URL or RelayRef must be set
+// +kubebuilder:validation:XValidation:rule="has(self.url) != has(self.relayRef)",message="exactly one of url or relayRef must be set"
 type RelayTarget struct {
 	// Name is the relay identifier
 	// +kubebuilder:validation:Required
 	Name string `json:"name"`
 
 	// URL is the WebSocket relay URL
-	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:Pattern=`^wss://.*`
-	URL string `json:"url"`
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// RelayRef references a ClusterRelay holding the relay endpoint
+	// +optional
+	RelayRef *ClusterRelayReference `json:"relayRef,omitempty"`
+
+	// CredentialsSecretRef references a Secret in the PortalExpose namespace holding the
+	// credentials of a private relay, such as an auth token or client key
+	// +optional
+	CredentialsSecretRef *RelayCredentialsReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// RelayCredentialsMountMode selects how relay credentials reach the tunnel container
+type RelayCredentialsMountMode string
+
+const (
+	// RelayCredentialsMountFile mounts every Secret key as a file under /etc/portal/relays/<target name>
+	RelayCredentialsMountFile RelayCredentialsMountMode = "File"
+
+	// RelayCredentialsMountEnv exposes every Secret key as a PORTAL_RELAY_<TARGET NAME>_<KEY> variable
+	RelayCredentialsMountEnv RelayCredentialsMountMode = "Env"
+)
+
+// RelayCredentialsReference references the Secret holding relay credentials
+type RelayCredentialsReference struct {
+	// Name is the Secret name
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// MountAs is File or Env
+	// +kubebuilder:validation:Enum=File;Env
+	// +kubebuilder:default=File
+	// +optional
+	MountAs RelayCredentialsMountMode `json:"mountAs,omitempty"`
+}
+
+// ClusterRelayReference references a cluster-scoped ClusterRelay
+type ClusterRelayReference struct {
+	// Name is the ClusterRelay name
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
 }
 
 // RelaySpec defines relay configuration
@@ -75,6 +140,14 @@ type PortalExposeSpec struct {
 	// +kubebuilder:validation:Required
 	App AppSpec `json:"app"`
 
+	// Endpoints are additional named endpoints published next to the app,
+	// each under its own subdomain and carried by the same tunnel Deployment
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=8
+	// +optional
+	Endpoints []EndpointSpec `json:"endpoints,omitempty"`
+
 	// Relay defines relay configuration
 	// +kubebuilder:validation:Required
 	Relay RelaySpec `json:"relay"`
@@ -116,6 +189,17 @@ type RelayConnectionStatus struct {
 	LastError string `json:"lastError,omitempty"`
 }
 
+// EndpointStatus reports where an endpoint is published
+type EndpointStatus struct {
+	// Name is the endpoint name ("app" for spec.app, else spec.endpoints[].name)
+	// +required
+	Name string `json:"name"`
+
+	// PublicURL is the accessible endpoint on the primary relay
+	// +optional
+	PublicURL string `json:"publicURL,omitempty"`
+}
+
 // RelayStatus represents the state of all relay connections
 type RelayStatus struct {
 	// Connected lists per-relay connection states
@@ -125,6 +209,10 @@ type RelayStatus struct {
 
 // PortalExposeStatus defines the observed state of PortalExpose.
 type PortalExposeStatus struct {
+	// ObservedGeneration is the last spec generation the status was computed from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
 	// Phase is the current state: Pending | Ready | Degraded | Failed
 	// +kubebuilder:validation:Enum=Pending;Ready;Degraded;Failed
 	// +optional
@@ -134,6 +222,12 @@ type PortalExposeStatus struct {
 	// +optional
 	PublicURL string `json:"publicURL,omitempty"`
 
+	// Endpoints lists the public URL of spec.app and every entry of spec.endpoints
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Endpoints []EndpointStatus `json:"endpoints,omitempty"`
+
 	// TunnelPods shows tunnel pod readiness
 	// +optional
 	TunnelPods TunnelPodStatus `json:"tunnelPods,omitempty"`
@@ -142,6 +236,11 @@ type PortalExposeStatus struct {
 	// +optional
 	Relay RelayStatus `json:"relay,omitempty"`
 
+	// IdentityFingerprint is the SHA-256 fingerprint of the tunnel identity public key,
+	// which relays use to recognize the exposure (e.g., "SHA256:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU")
+	// +optional
+	IdentityFingerprint string `json:"identityFingerprint,omitempty"`
+
 	// Conditions represent the current state of the PortalExpose resource.
 	// Standard condition types include:
 	// - "Available": the resource is fully functional
@@ -19,22 +19,36 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // TunnelClassSpec defines the desired state of TunnelClass
+// +kubebuilder:validation:XValidation:rule="has(self.size) != has(self.resources)",message="exactly one of size or resources must be set"
 type TunnelClassSpec struct {
 	// Replicas is the number of tunnel pod replicas
+	// Ignored while Autoscaling is set
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:Minimum=1
 	Replicas int32 `json:"replicas"`
 
-	// Size defines the resource allocation tier: small | medium | large
-	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:Enum=small;medium;large
-	Size string `json:"size"`
+	// AllowedNamespaces selects the namespaces whose PortalExposes may use this class
+	// Unset allows every namespace
+	// +optional
+	AllowedNamespaces *metav1.LabelSelector `json:"allowedNamespaces,omitempty"`
+
+	// Size defines the resource allocation tier: small | medium | large, or a tier
+	// added by the operator through the controller's size tier table
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	// +optional
+	Size string `json:"size,omitempty"`
+
+	// Resources sets the resources of each tunnel container explicitly, instead of a size tier
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
 
 	// NodeSelector constrains tunnel pods to nodes with specific labels
 	// +optional
@@ -43,6 +57,200 @@ type TunnelClassSpec struct {
 	// Tolerations allows tunnel pods to schedule on tainted nodes
 	// +optional
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// PodTemplate is merged into the tunnel pod template generated by the controller
+	// +optional
+	PodTemplate *TunnelPodTemplate `json:"podTemplate,omitempty"`
+
+	// Probes tunes the liveness and readiness probes of tunnel containers
+	// +optional
+	Probes *TunnelProbes `json:"probes,omitempty"`
+
+	// Autoscaling lets a HorizontalPodAutoscaler scale each tunnel Deployment
+	// +optional
+	Autoscaling *TunnelAutoscaling `json:"autoscaling,omitempty"`
+
+	// DisruptionBudget sets the policy of the PodDisruptionBudget guarding each tunnel Deployment
+	// Defaults to maxUnavailable 1, so node drains evict one tunnel pod at a time
+	// +optional
+	DisruptionBudget *TunnelDisruptionBudget `json:"disruptionBudget,omitempty"`
+
+	// NetworkPolicy restricts the traffic of tunnel pods with a generated NetworkPolicy
+	// +optional
+	NetworkPolicy *TunnelNetworkPolicy `json:"networkPolicy,omitempty"`
+
+	// SecurityOverride relaxes the restricted Pod Security defaults of tunnel pods
+	// It only takes effect with a portal.gosuda.org/security-override-reason annotation,
+	// which is recorded in an event and the RestrictedPodSecurity condition
+	// +optional
+	SecurityOverride *TunnelSecurityOverride `json:"securityOverride,omitempty"`
+}
+
+// TunnelAutoscaling configures the HorizontalPodAutoscaler of tunnel Deployments
+// CPU utilization is targeted at 80% when no target is set
+// +kubebuilder:validation:XValidation:rule="self.minReplicas <= self.maxReplicas",message="minReplicas must not exceed maxReplicas"
+type TunnelAutoscaling struct {
+	// MinReplicas is the lower replica bound, and the replica count of new tunnel Deployments
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas int32 `json:"minReplicas"`
+
+	// MaxReplicas is the upper replica bound
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// TargetCPUUtilizationPercentage is the average CPU utilization of tunnel pods, relative to their requests
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+
+	// TargetConnectionsPerPod is the average number of proxied connections per tunnel pod
+	// Requires a custom metrics adapter serving the portal_tunnel_active_connections pod metric
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetConnectionsPerPod *int32 `json:"targetConnectionsPerPod,omitempty"`
+}
+
+// TunnelNetworkPolicy configures the NetworkPolicy generated for each PortalExpose
+type TunnelNetworkPolicy struct {
+	// Enabled creates a NetworkPolicy allowing tunnel pods egress only to DNS, the relays and the
+	// backend Services, and ingress only from the controller to their status endpoints
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// RelayCIDRs limits relay egress to these address ranges
+	// NetworkPolicies cannot match host names, so relay egress is otherwise allowed to any address on the relay ports
+	// +optional
+	RelayCIDRs []string `json:"relayCIDRs,omitempty"`
+}
+
+// TunnelDisruptionBudget is the voluntary disruption policy of tunnel pods
+// +kubebuilder:validation:XValidation:rule="has(self.minAvailable) != has(self.maxUnavailable)",message="exactly one of minAvailable or maxUnavailable must be set"
+type TunnelDisruptionBudget struct {
+	// MinAvailable is the number or percentage of tunnel pods that must stay available during a disruption
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// MaxUnavailable is the number or percentage of tunnel pods that may be unavailable during a disruption
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// TunnelProbes configures the probes against the tunnel health endpoints
+type TunnelProbes struct {
+	// MinConnectedRelays is the number of relays a tunnel container must be connected to before it is ready
+	// Capped at the number of relay targets of the PortalExpose; defaults to 1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinConnectedRelays *int32 `json:"minConnectedRelays,omitempty"`
+
+	// Readiness tunes the readiness probe, which checks relay connectivity
+	// +optional
+	Readiness *ProbeThresholds `json:"readiness,omitempty"`
+
+	// Liveness tunes the liveness probe, which only checks the tunnel process
+	// +optional
+	Liveness *ProbeThresholds `json:"liveness,omitempty"`
+}
+
+// ProbeThresholds overrides the timing of a probe; unset fields keep the controller defaults
+type ProbeThresholds struct {
+	// InitialDelaySeconds is the delay before the first probe
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	InitialDelaySeconds *int32 `json:"initialDelaySeconds,omitempty"`
+
+	// PeriodSeconds is the interval between probes
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PeriodSeconds *int32 `json:"periodSeconds,omitempty"`
+
+	// TimeoutSeconds is the timeout of a single probe
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// FailureThreshold is the number of consecutive failures before the probe fails
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+}
+
+// TunnelSecurityOverride relaxes individual restricted Pod Security defaults
+// Unset fields keep the restricted default
+type TunnelSecurityOverride struct {
+	// RunAsNonRoot set to false allows the tunnel image to run as root
+	// +optional
+	RunAsNonRoot *bool `json:"runAsNonRoot,omitempty"`
+
+	// RunAsUser overrides the default non-root UID 65532
+	// +optional
+	RunAsUser *int64 `json:"runAsUser,omitempty"`
+
+	// ReadOnlyRootFilesystem set to false makes the container root filesystem writable
+	// +optional
+	ReadOnlyRootFilesystem *bool `json:"readOnlyRootFilesystem,omitempty"`
+
+	// AddCapabilities are added back after dropping ALL
+	// +optional
+	AddCapabilities []corev1.Capability `json:"addCapabilities,omitempty"`
+
+	// SeccompProfile replaces the RuntimeDefault seccomp profile
+	// +optional
+	SeccompProfile *corev1.SeccompProfile `json:"seccompProfile,omitempty"`
+
+	// AutomountServiceAccountToken set to true mounts the ServiceAccount token into tunnel pods
+	// +optional
+	AutomountServiceAccountToken *bool `json:"automountServiceAccountToken,omitempty"`
+}
+
+// TunnelPodTemplate overlays the tunnel pod template
+// Containers, volumes and controller labels stay owned by the controller and cannot be overridden
+type TunnelPodTemplate struct {
+	// Metadata adds labels and annotations to tunnel pods
+	// +optional
+	Metadata TunnelPodMetadata `json:"metadata,omitempty"`
+
+	// Spec sets scheduling and identity fields of tunnel pods
+	// +optional
+	Spec TunnelPodSpec `json:"spec,omitempty"`
+}
+
+// TunnelPodMetadata holds extra labels and annotations for tunnel pods
+type TunnelPodMetadata struct {
+	// Labels are added to tunnel pods; app.kubernetes.io/* selector labels and
+	// portal.gosuda.org/* keys are reserved for the controller
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to tunnel pods; portal.gosuda.org/* keys are reserved for the controller
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// TunnelPodSpec holds the pod spec fields a TunnelClass may set on tunnel pods
+type TunnelPodSpec struct {
+	// Affinity sets node, pod affinity and anti-affinity rules
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// TopologySpreadConstraints spread tunnel pods across topology domains
+	// Use matchLabelKeys with portal.gosuda.org/portalexpose to spread each exposure separately
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// PriorityClassName sets the priority of tunnel pods
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// ImagePullSecrets are used to pull the tunnel image, e.g. through a registry mirror
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// ServiceAccountName runs tunnel pods as this ServiceAccount of the PortalExpose namespace
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
 }
 
 // TunnelClassStatus defines the observed state of TunnelClass.
@@ -52,6 +260,10 @@ type TunnelClassStatus struct {
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 
+	// ExposureCount is the number of PortalExposes using this class, by name, as their namespace default or as the default
+	// +optional
+	ExposureCount int32 `json:"exposureCount,omitempty"`
+
 	// Conditions represent the current state of the TunnelClass resource.
 	// +listType=map
 	// +listMapKey=type
@@ -61,6 +273,7 @@ type TunnelClassStatus struct {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
 
 // TunnelClass is the Schema for the tunnelclasses API
 type TunnelClass struct {
@@ -0,0 +1,140 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoexpose
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+const (
+	// ExposeAnnotation opts a Service into automatic exposure when set to "true"
+	ExposeAnnotation = "portal.gosuda.org/expose"
+
+	// AppNameAnnotation sets spec.app.name; defaults to the Service name
+	AppNameAnnotation = "portal.gosuda.org/app-name"
+
+	// PortAnnotation sets the Service port name or number; defaults to the first Service port
+	PortAnnotation = "portal.gosuda.org/port"
+
+	// RelayAnnotation lists the relays as comma-separated wss:// URLs or ClusterRelay names
+	RelayAnnotation = "portal.gosuda.org/relay"
+
+	// TunnelClassAnnotation sets spec.tunnelClassName; the default TunnelClass is used if omitted
+	TunnelClassAnnotation = "portal.gosuda.org/tunnel-class"
+
+	// ServiceLabel records the Service a PortalExpose was generated for
+	ServiceLabel = "portal.gosuda.org/service"
+)
+
+// IsExposed reports whether the Service opted into automatic exposure
+func IsExposed(service *corev1.Service) bool {
+	return service.Annotations[ExposeAnnotation] == "true"
+}
+
+// BuildPortalExpose generates the PortalExpose described by the Service annotations
+// The PortalExpose has the Service's name and namespace
+func BuildPortalExpose(service *corev1.Service) (*portalv1alpha1.PortalExpose, error) {
+	appName := service.Annotations[AppNameAnnotation]
+	if appName == "" {
+		appName = service.Name
+	}
+
+	port, err := servicePort(service)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := ParseRelayTargets(service.Annotations[RelayAnnotation])
+	if err != nil {
+		return nil, err
+	}
+
+	return &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      service.Name,
+			Namespace: service.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "portal-expose-controller",
+				ServiceLabel:                   service.Name,
+			},
+		},
+		Spec: portalv1alpha1.PortalExposeSpec{
+			App: portalv1alpha1.AppSpec{
+				Name: appName,
+				Service: portalv1alpha1.ServiceRef{
+					Name: service.Name,
+					Port: port,
+				},
+			},
+			Relay: portalv1alpha1.RelaySpec{
+				Targets: targets,
+			},
+			TunnelClassName: service.Annotations[TunnelClassAnnotation],
+		},
+	}, nil
+}
+
+// servicePort returns the port annotation or the first Service port
+func servicePort(service *corev1.Service) (intstr.IntOrString, error) {
+	if value := strings.TrimSpace(service.Annotations[PortAnnotation]); value != "" {
+		return intstr.Parse(value), nil
+	}
+	if len(service.Spec.Ports) == 0 {
+		return intstr.IntOrString{}, fmt.Errorf("service has no ports and no %s annotation", PortAnnotation)
+	}
+	return intstr.FromInt32(service.Spec.Ports[0].Port), nil
+}
+
+// ParseRelayTargets parses a relay annotation value into relay targets
+// wss:// entries become inline URLs, any other entry references a ClusterRelay
+func ParseRelayTargets(value string) ([]portalv1alpha1.RelayTarget, error) {
+	var targets []portalv1alpha1.RelayTarget
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "://") {
+			if !strings.HasPrefix(entry, "wss://") {
+				return nil, fmt.Errorf("relay %q in %s must be a wss:// URL or a ClusterRelay name", entry, RelayAnnotation)
+			}
+			targets = append(targets, portalv1alpha1.RelayTarget{
+				Name: fmt.Sprintf("relay-%d", len(targets)),
+				URL:  entry,
+			})
+			continue
+		}
+
+		targets = append(targets, portalv1alpha1.RelayTarget{
+			Name:     entry,
+			RelayRef: &portalv1alpha1.ClusterRelayReference{Name: entry},
+		})
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("annotation %s must list at least one relay", RelayAnnotation)
+	}
+	return targets, nil
+}
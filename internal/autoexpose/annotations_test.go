@@ -0,0 +1,96 @@
+package autoexpose
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func testService(annotations map[string]string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps", Annotations: annotations},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80}, {Name: "admin", Port: 9000}},
+		},
+	}
+}
+
+func TestBuildPortalExpose(t *testing.T) {
+	service := testService(map[string]string{
+		ExposeAnnotation:      "true",
+		AppNameAnnotation:     "my-web",
+		PortAnnotation:        "admin",
+		RelayAnnotation:       "wss://portal.gosuda.org/relay, shared-relay",
+		TunnelClassAnnotation: "production",
+	})
+
+	portalExpose, err := BuildPortalExpose(service)
+	if err != nil {
+		t.Fatalf("BuildPortalExpose() error = %v", err)
+	}
+
+	if portalExpose.Name != "web" || portalExpose.Namespace != "apps" {
+		t.Errorf("BuildPortalExpose() key = %s/%s, want apps/web", portalExpose.Namespace, portalExpose.Name)
+	}
+	if portalExpose.Spec.App.Name != "my-web" {
+		t.Errorf("BuildPortalExpose() app name = %v, want my-web", portalExpose.Spec.App.Name)
+	}
+	if portalExpose.Spec.App.Service.Port != intstr.FromString("admin") {
+		t.Errorf("BuildPortalExpose() port = %v, want admin", portalExpose.Spec.App.Service.Port.String())
+	}
+	if portalExpose.Spec.TunnelClassName != "production" {
+		t.Errorf("BuildPortalExpose() tunnelClassName = %v, want production", portalExpose.Spec.TunnelClassName)
+	}
+
+	targets := portalExpose.Spec.Relay.Targets
+	if len(targets) != 2 {
+		t.Fatalf("BuildPortalExpose() targets = %+v, want 2", targets)
+	}
+	if targets[0].URL != "wss://portal.gosuda.org/relay" || targets[0].RelayRef != nil {
+		t.Errorf("Target[0] = %+v, want inline URL", targets[0])
+	}
+	if targets[1].RelayRef == nil || targets[1].RelayRef.Name != "shared-relay" || targets[1].URL != "" {
+		t.Errorf("Target[1] = %+v, want ClusterRelay reference", targets[1])
+	}
+}
+
+func TestBuildPortalExposeDefaults(t *testing.T) {
+	service := testService(map[string]string{
+		ExposeAnnotation: "true",
+		RelayAnnotation:  "shared-relay",
+	})
+
+	portalExpose, err := BuildPortalExpose(service)
+	if err != nil {
+		t.Fatalf("BuildPortalExpose() error = %v", err)
+	}
+	if portalExpose.Spec.App.Name != "web" {
+		t.Errorf("BuildPortalExpose() app name = %v, want the Service name", portalExpose.Spec.App.Name)
+	}
+	if portalExpose.Spec.App.Service.Port != intstr.FromInt32(80) {
+		t.Errorf("BuildPortalExpose() port = %v, want the first Service port", portalExpose.Spec.App.Service.Port.String())
+	}
+	if portalExpose.Spec.TunnelClassName != "" {
+		t.Errorf("BuildPortalExpose() tunnelClassName = %v, want default class", portalExpose.Spec.TunnelClassName)
+	}
+}
+
+func TestBuildPortalExposeInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+	}{
+		{name: "No relay", annotations: map[string]string{ExposeAnnotation: "true"}},
+		{name: "Insecure relay URL", annotations: map[string]string{ExposeAnnotation: "true", RelayAnnotation: "ws://relay.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BuildPortalExpose(testService(tt.annotations)); err == nil {
+				t.Error("BuildPortalExpose() expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoexpose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+const (
+	// IngressController is the IngressClass spec.controller handled by portal-expose
+	IngressController = "portal.gosuda.org/ingress-controller"
+
+	// IngressLabel records the Ingress a PortalExpose was generated for
+	IngressLabel = "portal.gosuda.org/ingress"
+
+	// legacyIngressClassAnnotation selects the class of Ingresses predating spec.ingressClassName
+	legacyIngressClassAnnotation = "kubernetes.io/ingress.class"
+
+	// maxEndpointNameLength is the MaxLength of spec.endpoints[].name
+	maxEndpointNameLength = 50
+)
+
+// IngressPortalExposeName returns the name of the PortalExpose generated for an Ingress
+// The suffix keeps it apart from the PortalExpose of an annotated Service of the same name
+func IngressPortalExposeName(ingress *networkingv1.Ingress) string {
+	return ingress.Name + "-ingress"
+}
+
+// HostAppName maps a host to a Portal app name, its first DNS label
+// Wildcard hosts cannot be published and map to ""
+func HostAppName(host string) string {
+	label, _, _ := strings.Cut(host, ".")
+	if label == "*" {
+		return ""
+	}
+	return label
+}
+
+// HostEndpointName derives the name of the endpoint publishing a host from its app name,
+// so reordering the hosts keeps every endpoint and its tunnel container
+// App names that are reserved for spec.app or too long for an endpoint name are shortened and suffixed with a hash of the host
+func HostEndpointName(host, appName string) string {
+	if appName != "app" && len(appName) <= maxEndpointNameLength {
+		return appName
+	}
+	sum := sha256.Sum256([]byte(host))
+	suffix := hex.EncodeToString(sum[:4])
+	prefix := strings.TrimRight(appName[:min(len(appName), maxEndpointNameLength-len(suffix)-1)], "-")
+	return prefix + "-" + suffix
+}
+
+// IngressClassName returns the class requested by the Ingress, or "" to use the default IngressClass
+func IngressClassName(ingress *networkingv1.Ingress) string {
+	if ingress.Spec.IngressClassName != nil {
+		return *ingress.Spec.IngressClassName
+	}
+	return ingress.Annotations[legacyIngressClassAnnotation]
+}
+
+// IsPortalIngressClass reports whether the IngressClass is handled by portal-expose
+func IsPortalIngressClass(class *networkingv1.IngressClass) bool {
+	return class.Spec.Controller == IngressController
+}
+
+// IngressRelayTargets returns the relays an Ingress publishes on: its portal.gosuda.org/relay
+// annotation, or else the ClusterRelay referenced by its IngressClass parameters
+func IngressRelayTargets(ingress *networkingv1.Ingress, class *networkingv1.IngressClass) ([]portalv1alpha1.RelayTarget, error) {
+	if value, ok := ingress.Annotations[RelayAnnotation]; ok {
+		return ParseRelayTargets(value)
+	}
+
+	ref := class.Spec.Parameters
+	if ref == nil {
+		return nil, fmt.Errorf("set the %s annotation on the Ingress or point IngressClass '%s' parameters at a ClusterRelay",
+			RelayAnnotation, class.Name)
+	}
+	if ref.APIGroup == nil || *ref.APIGroup != portalv1alpha1.GroupVersion.Group || ref.Kind != "ClusterRelay" {
+		return nil, fmt.Errorf("IngressClass '%s' parameters must reference a %s ClusterRelay",
+			class.Name, portalv1alpha1.GroupVersion.Group)
+	}
+	return []portalv1alpha1.RelayTarget{
+		{Name: ref.Name, RelayRef: &portalv1alpha1.ClusterRelayReference{Name: ref.Name}},
+	}, nil
+}
+
+// BuildIngressPortalExpose generates the PortalExpose publishing the Ingress
+// Every host is published under its first DNS label, see HostAppName; the first host is spec.app.
+// A tunnel forwards a whole host to one Service port, so all paths of a host must share a backend.
+func BuildIngressPortalExpose(ingress *networkingv1.Ingress, targets []portalv1alpha1.RelayTarget) (*portalv1alpha1.PortalExpose, error) {
+	var hosts []string
+	appNames := map[string]string{}
+	hostsByAppName := map[string]string{}
+	services := map[string]portalv1alpha1.ServiceRef{}
+	addHost := func(host string, backend *networkingv1.IngressBackend) error {
+		appName := ingress.Name
+		if host != "" {
+			appName = HostAppName(host)
+			if appName == "" {
+				return fmt.Errorf("host '%s' cannot be published; wildcard hosts are not supported", host)
+			}
+		}
+
+		service, err := ingressService(backend)
+		if err != nil {
+			return err
+		}
+		if existing, ok := services[host]; ok {
+			if existing != service {
+				return fmt.Errorf("host '%s' routes to several backends; tunnels forward a whole host to one Service port", host)
+			}
+			return nil
+		}
+		if other, ok := hostsByAppName[appName]; ok {
+			return fmt.Errorf("hosts '%s' and '%s' would both be published as '%s'; the first DNS labels of the hosts must differ",
+				other, host, appName)
+		}
+		services[host] = service
+		appNames[host] = appName
+		hostsByAppName[appName] = host
+		hosts = append(hosts, host)
+		return nil
+	}
+
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil || len(rule.HTTP.Paths) == 0 {
+			if ingress.Spec.DefaultBackend == nil {
+				return nil, fmt.Errorf("rule for host '%s' has no paths and the Ingress has no default backend", rule.Host)
+			}
+			if err := addHost(rule.Host, ingress.Spec.DefaultBackend); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for i := range rule.HTTP.Paths {
+			if err := addHost(rule.Host, &rule.HTTP.Paths[i].Backend); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// A default backend is only published on its own; with rules it has no host to serve
+	if len(hosts) == 0 {
+		if ingress.Spec.DefaultBackend == nil {
+			return nil, fmt.Errorf("ingress has no rules and no default backend")
+		}
+		if err := addHost("", ingress.Spec.DefaultBackend); err != nil {
+			return nil, err
+		}
+	}
+
+	var endpoints []portalv1alpha1.EndpointSpec
+	for _, host := range hosts[1:] {
+		endpoints = append(endpoints, portalv1alpha1.EndpointSpec{
+			Name:      HostEndpointName(host, appNames[host]),
+			Subdomain: appNames[host],
+			Service:   services[host],
+		})
+	}
+
+	return &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      IngressPortalExposeName(ingress),
+			Namespace: ingress.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "portal-expose-controller",
+				IngressLabel:                   ingress.Name,
+			},
+		},
+		Spec: portalv1alpha1.PortalExposeSpec{
+			App: portalv1alpha1.AppSpec{
+				Name:    appNames[hosts[0]],
+				Service: services[hosts[0]],
+			},
+			Endpoints: endpoints,
+			Relay: portalv1alpha1.RelaySpec{
+				Targets: targets,
+			},
+			TunnelClassName: ingress.Annotations[TunnelClassAnnotation],
+		},
+	}, nil
+}
+
+// ingressService converts an Ingress backend into a Service reference
+func ingressService(backend *networkingv1.IngressBackend) (portalv1alpha1.ServiceRef, error) {
+	if backend.Service == nil {
+		return portalv1alpha1.ServiceRef{}, fmt.Errorf("resource backends are not supported; use a Service backend")
+	}
+
+	port := intstr.FromString(backend.Service.Port.Name)
+	if backend.Service.Port.Name == "" {
+		port = intstr.FromInt32(backend.Service.Port.Number)
+	}
+	return portalv1alpha1.ServiceRef{Name: backend.Service.Name, Port: port}, nil
+}
+
+// IngressLoadBalancer returns the Ingress load balancer status for the public URLs of the PortalExpose
+func IngressLoadBalancer(portalExpose *portalv1alpha1.PortalExpose) networkingv1.IngressLoadBalancerStatus {
+	publicURLs := []string{portalExpose.Status.PublicURL}
+	for _, endpoint := range portalExpose.Status.Endpoints {
+		publicURLs = append(publicURLs, endpoint.PublicURL)
+	}
+
+	var status networkingv1.IngressLoadBalancerStatus
+	seen := map[string]bool{}
+	for _, publicURL := range publicURLs {
+		parsed, err := url.Parse(publicURL)
+		if publicURL == "" || err != nil || parsed.Hostname() == "" || seen[parsed.Hostname()] {
+			continue
+		}
+		seen[parsed.Hostname()] = true
+		status.Ingress = append(status.Ingress, networkingv1.IngressLoadBalancerIngress{Hostname: parsed.Hostname()})
+	}
+	return status
+}
@@ -0,0 +1,212 @@
+package autoexpose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+func ingressPath(service string, port networkingv1.ServiceBackendPort) networkingv1.HTTPIngressPath {
+	pathType := networkingv1.PathTypePrefix
+	return networkingv1.HTTPIngressPath{
+		Path:     "/",
+		PathType: &pathType,
+		Backend: networkingv1.IngressBackend{
+			Service: &networkingv1.IngressServiceBackend{Name: service, Port: port},
+		},
+	}
+}
+
+func ingressRule(host string, paths ...networkingv1.HTTPIngressPath) networkingv1.IngressRule {
+	return networkingv1.IngressRule{
+		Host: host,
+		IngressRuleValue: networkingv1.IngressRuleValue{
+			HTTP: &networkingv1.HTTPIngressRuleValue{Paths: paths},
+		},
+	}
+}
+
+func testIngress(rules ...networkingv1.IngressRule) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec:       networkingv1.IngressSpec{Rules: rules},
+	}
+}
+
+func TestBuildIngressPortalExpose(t *testing.T) {
+	ingress := testIngress(
+		ingressRule("my-app.portal.gosuda.org",
+			ingressPath("web-svc", networkingv1.ServiceBackendPort{Number: 8080}),
+			ingressPath("web-svc", networkingv1.ServiceBackendPort{Number: 8080})),
+		ingressRule("admin.portal.gosuda.org",
+			ingressPath("admin-svc", networkingv1.ServiceBackendPort{Name: "http"})),
+	)
+	ingress.Annotations = map[string]string{TunnelClassAnnotation: "production"}
+	targets := []portalv1alpha1.RelayTarget{{Name: "gosuda", URL: "wss://portal.gosuda.org/relay"}}
+
+	portalExpose, err := BuildIngressPortalExpose(ingress, targets)
+	if err != nil {
+		t.Fatalf("BuildIngressPortalExpose() error = %v", err)
+	}
+	if portalExpose.Name != "web-ingress" || portalExpose.Namespace != "apps" {
+		t.Errorf("BuildIngressPortalExpose() key = %s/%s, want apps/web-ingress", portalExpose.Namespace, portalExpose.Name)
+	}
+	if portalExpose.Spec.App.Name != "my-app" || portalExpose.Spec.App.Service.Port != intstr.FromInt32(8080) {
+		t.Errorf("BuildIngressPortalExpose() app = %+v, want my-app on web-svc:8080", portalExpose.Spec.App)
+	}
+	if len(portalExpose.Spec.Endpoints) != 1 {
+		t.Fatalf("BuildIngressPortalExpose() endpoints = %+v, want 1", portalExpose.Spec.Endpoints)
+	}
+	endpoint := portalExpose.Spec.Endpoints[0]
+	if endpoint.Name != "admin" || endpoint.Subdomain != "admin" ||
+		endpoint.Service.Name != "admin-svc" || endpoint.Service.Port != intstr.FromString("http") {
+		t.Errorf("Endpoint = %+v, want admin on admin-svc:http", endpoint)
+	}
+	if portalExpose.Spec.TunnelClassName != "production" {
+		t.Errorf("BuildIngressPortalExpose() tunnelClassName = %v, want production", portalExpose.Spec.TunnelClassName)
+	}
+}
+
+func TestBuildIngressPortalExposeDefaultBackend(t *testing.T) {
+	ingress := testIngress()
+	ingress.Spec.DefaultBackend = &networkingv1.IngressBackend{
+		Service: &networkingv1.IngressServiceBackend{Name: "web-svc", Port: networkingv1.ServiceBackendPort{Number: 80}},
+	}
+
+	portalExpose, err := BuildIngressPortalExpose(ingress, nil)
+	if err != nil {
+		t.Fatalf("BuildIngressPortalExpose() error = %v", err)
+	}
+	if portalExpose.Spec.App.Name != "web" || portalExpose.Spec.App.Service.Name != "web-svc" {
+		t.Errorf("BuildIngressPortalExpose() app = %+v, want the Ingress name on web-svc", portalExpose.Spec.App)
+	}
+}
+
+func TestBuildIngressPortalExposeInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		ingress *networkingv1.Ingress
+	}{
+		{
+			name: "Wildcard host",
+			ingress: testIngress(ingressRule("*.portal.gosuda.org",
+				ingressPath("web-svc", networkingv1.ServiceBackendPort{Number: 80}))),
+		},
+		{
+			name: "Path split",
+			ingress: testIngress(ingressRule("my-app.portal.gosuda.org",
+				ingressPath("web-svc", networkingv1.ServiceBackendPort{Number: 80}),
+				ingressPath("api-svc", networkingv1.ServiceBackendPort{Number: 80}))),
+		},
+		{
+			name: "Resource backend",
+			ingress: testIngress(ingressRule("my-app.portal.gosuda.org", networkingv1.HTTPIngressPath{
+				Path:    "/",
+				Backend: networkingv1.IngressBackend{Resource: &corev1.TypedLocalObjectReference{Kind: "Bucket", Name: "assets"}},
+			})),
+		},
+		{
+			name: "Hosts sharing the first DNS label",
+			ingress: testIngress(
+				ingressRule("api.a.example.com", ingressPath("a-svc", networkingv1.ServiceBackendPort{Number: 80})),
+				ingressRule("api.b.example.com", ingressPath("b-svc", networkingv1.ServiceBackendPort{Number: 80}))),
+		},
+		{
+			name:    "Empty",
+			ingress: testIngress(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BuildIngressPortalExpose(tt.ingress, nil); err == nil {
+				t.Error("BuildIngressPortalExpose() expected error")
+			}
+		})
+	}
+}
+
+func TestHostEndpointName(t *testing.T) {
+	long := strings.Repeat("a", 60)
+	tests := []struct {
+		name    string
+		host    string
+		appName string
+		want    string
+	}{
+		{name: "App name", host: "admin.example.com", appName: "admin", want: "admin"},
+		{name: "Reserved app name", host: "app.example.com", appName: "app", want: "app-" + hostHash("app.example.com")},
+		{name: "Long app name", host: long + ".example.com", appName: long, want: long[:41] + "-" + hostHash(long+".example.com")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HostEndpointName(tt.host, tt.appName); got != tt.want {
+				t.Errorf("HostEndpointName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func hostHash(host string) string {
+	sum := sha256.Sum256([]byte(host))
+	return hex.EncodeToString(sum[:4])
+}
+
+func TestIngressRelayTargets(t *testing.T) {
+	group := portalv1alpha1.GroupVersion.Group
+	class := &networkingv1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "portal"},
+		Spec: networkingv1.IngressClassSpec{
+			Controller: IngressController,
+			Parameters: &networkingv1.IngressClassParametersReference{APIGroup: &group, Kind: "ClusterRelay", Name: "shared-relay"},
+		},
+	}
+
+	targets, err := IngressRelayTargets(testIngress(), class)
+	if err != nil || len(targets) != 1 || targets[0].RelayRef == nil || targets[0].RelayRef.Name != "shared-relay" {
+		t.Errorf("IngressRelayTargets() = %+v, %v, want the class ClusterRelay", targets, err)
+	}
+
+	ingress := testIngress()
+	ingress.Annotations = map[string]string{RelayAnnotation: "wss://portal.gosuda.org/relay"}
+	targets, err = IngressRelayTargets(ingress, class)
+	if err != nil || len(targets) != 1 || targets[0].URL != "wss://portal.gosuda.org/relay" {
+		t.Errorf("IngressRelayTargets() = %+v, %v, want the Ingress annotation to win", targets, err)
+	}
+
+	class.Spec.Parameters = nil
+	if _, err := IngressRelayTargets(testIngress(), class); err == nil {
+		t.Error("IngressRelayTargets() expected error without relays")
+	}
+}
+
+func TestIngressLoadBalancer(t *testing.T) {
+	portalExpose := &portalv1alpha1.PortalExpose{
+		Status: portalv1alpha1.PortalExposeStatus{
+			PublicURL: "https://my-app.portal.gosuda.org",
+			Endpoints: []portalv1alpha1.EndpointStatus{
+				{Name: "app", PublicURL: "https://my-app.portal.gosuda.org"},
+				{Name: "admin", PublicURL: "https://admin.portal.gosuda.org"},
+			},
+		},
+	}
+
+	status := IngressLoadBalancer(portalExpose)
+	if len(status.Ingress) != 2 || status.Ingress[0].Hostname != "my-app.portal.gosuda.org" ||
+		status.Ingress[1].Hostname != "admin.portal.gosuda.org" {
+		t.Errorf("IngressLoadBalancer() = %+v, want my-app and admin hostnames", status.Ingress)
+	}
+
+	if status := IngressLoadBalancer(&portalv1alpha1.PortalExpose{}); len(status.Ingress) != 0 {
+		t.Errorf("IngressLoadBalancer() = %+v, want empty without a public URL", status.Ingress)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterrelay
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/tunnel"
+)
+
+// ResolveTargets resolves relay targets to concrete endpoints
+// Inline targets use their URL as-is; targets with relayRef take the URL and
+// public domain from the referenced ClusterRelay
+func ResolveTargets(ctx context.Context, c client.Client, targets []portalv1alpha1.RelayTarget) ([]tunnel.RelayEndpoint, error) {
+	endpoints := make([]tunnel.RelayEndpoint, 0, len(targets))
+
+	for _, target := range targets {
+		if target.RelayRef == nil {
+			endpoints = append(endpoints, tunnel.RelayEndpoint{
+				Name:         target.Name,
+				URL:          target.URL,
+				PublicDomain: tunnel.RelayDomain(target.URL),
+				Credentials:  target.CredentialsSecretRef,
+			})
+			continue
+		}
+
+		clusterRelay := &portalv1alpha1.ClusterRelay{}
+		if err := c.Get(ctx, client.ObjectKey{Name: target.RelayRef.Name}, clusterRelay); err != nil {
+			return nil, fmt.Errorf("failed to get ClusterRelay %q for relay %q: %w", target.RelayRef.Name, target.Name, err)
+		}
+
+		publicDomain := clusterRelay.Spec.PublicDomain
+		if publicDomain == "" {
+			publicDomain = tunnel.RelayDomain(clusterRelay.Spec.URL)
+		}
+		endpoints = append(endpoints, tunnel.RelayEndpoint{
+			Name:         target.Name,
+			URL:          clusterRelay.Spec.URL,
+			PublicDomain: publicDomain,
+			ClusterRelay: clusterRelay.Name,
+			Credentials:  target.CredentialsSecretRef,
+		})
+	}
+
+	return endpoints, nil
+}
+
+// ReferencedNames returns the names of the ClusterRelays referenced by a PortalExpose
+func ReferencedNames(portalExpose *portalv1alpha1.PortalExpose) []string {
+	var names []string
+	for _, target := range portalExpose.Spec.Relay.Targets {
+		if target.RelayRef != nil {
+			names = append(names, target.RelayRef.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/clusterrelay"
+)
+
+// ClusterRelayReconciler reconciles a ClusterRelay object
+type ClusterRelayReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=portal.gosuda.org,resources=clusterrelays,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=portal.gosuda.org,resources=clusterrelays/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=portal.gosuda.org,resources=clusterrelays/finalizers,verbs=update
+
+// Reconcile publishes how many PortalExposes use a ClusterRelay.
+// Rolling relay changes out to tunnel Deployments is done by the PortalExpose controller.
+func (r *ClusterRelayReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	// Fetch the ClusterRelay
+	clusterRelay := &portalv1alpha1.ClusterRelay{}
+	if err := r.Get(ctx, req.NamespacedName, clusterRelay); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "unable to fetch ClusterRelay")
+			return ctrl.Result{}, err
+		}
+		// Resource deleted - nothing to do
+		return ctrl.Result{}, nil
+	}
+
+	// Count PortalExposes referencing this relay
+	portalExposes := &portalv1alpha1.PortalExposeList{}
+	if err := r.List(ctx, portalExposes, client.MatchingFields{clusterRelayRefIndex: clusterRelay.Name}); err != nil {
+		log.Error(err, "unable to list PortalExposes referencing ClusterRelay")
+		return ctrl.Result{}, err
+	}
+	exposureCount := int32(len(portalExposes.Items))
+
+	if clusterRelay.Status.ExposureCount == exposureCount &&
+		clusterRelay.Status.ObservedGeneration == clusterRelay.Generation {
+		return ctrl.Result{}, nil
+	}
+
+	err := patchStatusWithRetry(ctx, r.Client, clusterRelay, func(latest *portalv1alpha1.ClusterRelay) error {
+		latest.Status.ExposureCount = exposureCount
+		latest.Status.ObservedGeneration = latest.Generation
+		return nil
+	})
+	if err != nil {
+		log.Error(err, "unable to update ClusterRelay status")
+		return ctrl.Result{}, err
+	}
+
+	log.V(1).Info("ClusterRelay reconciled", "name", clusterRelay.Name, "exposureCount", exposureCount)
+	return ctrl.Result{}, nil
+}
+
+// clusterRelaysForPortalExpose maps a PortalExpose to the ClusterRelays it references
+func clusterRelaysForPortalExpose(_ context.Context, obj client.Object) []reconcile.Request {
+	portalExpose, ok := obj.(*portalv1alpha1.PortalExpose)
+	if !ok {
+		return nil
+	}
+
+	names := clusterrelay.ReferencedNames(portalExpose)
+	requests := make([]reconcile.Request, 0, len(names))
+	for _, name := range names {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKey{Name: name}})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// Requires the field indexes registered by SetupIndexes.
+func (r *ClusterRelayReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&portalv1alpha1.ClusterRelay{}).
+		// Keep exposure counts current; relay references only change with the spec, not with status updates
+		Watches(&portalv1alpha1.PortalExpose{},
+			handler.EnqueueRequestsFromMapFunc(clusterRelaysForPortalExpose),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Named("clusterrelay").
+		Complete(r)
+}
@@ -0,0 +1,348 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/clusterrelay"
+	"github.com/gosuda/portal-expose/internal/gateway"
+)
+
+// GatewayClassReconciler accepts GatewayClasses whose controllerName is gateway.ControllerName
+type GatewayClassReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gatewayclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gatewayclasses/status,verbs=get;update;patch
+
+// Reconcile marks portal GatewayClasses as Accepted
+func (r *GatewayClassReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	obj := gateway.NewUnstructured(gateway.GatewayClassGVK)
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	class := &gateway.GatewayClass{}
+	if err := gateway.FromUnstructured(obj, class); err != nil {
+		logger.Error(err, "Failed to decode GatewayClass")
+		return ctrl.Result{}, err
+	}
+	if class.Spec.ControllerName != gateway.ControllerName {
+		return ctrl.Result{}, nil
+	}
+
+	accepted := false
+	err := patchStatusWithRetry(ctx, r.Client, obj, func(latest *unstructured.Unstructured) error {
+		status := &gateway.GatewayClassStatus{}
+		if err := decodeStatus(latest, status); err != nil {
+			return err
+		}
+		accepted = meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               "Accepted",
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: latest.GetGeneration(),
+			Reason:             "Accepted",
+			Message:            "Handled by portal-expose",
+		})
+		if !accepted {
+			return nil
+		}
+		return gateway.SetStatus(latest, status)
+	})
+	if err != nil {
+		logger.Error(err, "Failed to update GatewayClass status")
+		return ctrl.Result{}, err
+	}
+	if accepted {
+		logger.Info("Accepted GatewayClass", "name", class.Name)
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GatewayClassReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(gateway.NewUnstructured(gateway.GatewayClassGVK)).
+		Named("gatewayclass").
+		Complete(r)
+}
+
+// GatewayReconciler reports the relays of portal Gateways as their addresses
+type GatewayReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=portal.gosuda.org,resources=clusterrelays,verbs=get;list;watch
+
+// Reconcile computes the status of a Gateway whose GatewayClass is handled by portal-expose
+func (r *GatewayReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	obj := gateway.NewUnstructured(gateway.GatewayGVK)
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	gw := &gateway.Gateway{}
+	if err := gateway.FromUnstructured(obj, gw); err != nil {
+		logger.Error(err, "Failed to decode Gateway")
+		return ctrl.Result{}, err
+	}
+	class, err := getPortalGatewayClass(ctx, r.Client, gw.Spec.GatewayClassName)
+	if err != nil || class == nil {
+		return ctrl.Result{}, err
+	}
+
+	status := &gateway.GatewayStatus{}
+	if err := decodeStatus(obj, status); err != nil {
+		logger.Error(err, "Failed to decode Gateway status")
+		return ctrl.Result{}, err
+	}
+
+	setCondition := func(conditions *[]metav1.Condition, conditionType string, ok bool, reason, message string) {
+		conditionStatus := metav1.ConditionFalse
+		if ok {
+			conditionStatus = metav1.ConditionTrue
+		}
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               conditionType,
+			Status:             conditionStatus,
+			ObservedGeneration: gw.Generation,
+			Reason:             reason,
+			Message:            message,
+		})
+	}
+
+	// Resolve the relays the Gateway publishes on, the same way PortalExposes do
+	accepted, programmed := true, true
+	programmedReason, programmedMessage := "Programmed", ""
+	status.Addresses = nil
+	targets, err := gateway.RelayTargets(gw, class)
+	var conditionErr *gateway.ConditionError
+	switch {
+	case goerrors.As(err, &conditionErr):
+		accepted, programmed = false, false
+		setCondition(&status.Conditions, "Accepted", false, conditionErr.Reason, conditionErr.Message)
+		programmedReason, programmedMessage = "Invalid", conditionErr.Message
+	case err != nil:
+		return ctrl.Result{}, err
+	default:
+		setCondition(&status.Conditions, "Accepted", true, "Accepted", "Gateway is handled by portal-expose")
+
+		relays, err := clusterrelay.ResolveTargets(ctx, r.Client, targets)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				logger.Error(err, "Failed to resolve relay targets")
+				return ctrl.Result{}, err
+			}
+			programmed = false
+			programmedReason, programmedMessage = "AddressNotAssigned", err.Error()
+		} else {
+			hostname := "Hostname"
+			seen := make(map[string]bool, len(relays))
+			for _, relay := range relays {
+				if !seen[relay.PublicDomain] {
+					seen[relay.PublicDomain] = true
+					status.Addresses = append(status.Addresses, gateway.GatewayStatusAddress{Type: &hostname, Value: relay.PublicDomain})
+				}
+			}
+			programmedMessage = fmt.Sprintf("Publishing on %d relays", len(relays))
+		}
+	}
+	setCondition(&status.Conditions, "Programmed", programmed, programmedReason, programmedMessage)
+
+	// Listener status, with the routes attached to each listener
+	routes, err := r.attachedRoutes(ctx, req.NamespacedName)
+	if err != nil {
+		logger.Error(err, "Failed to list HTTPRoutes")
+		return ctrl.Result{}, err
+	}
+	routeGroup := gateway.Group
+	listeners := make([]gateway.ListenerStatus, 0, len(gw.Spec.Listeners))
+	for _, listener := range gw.Spec.Listeners {
+		listenerStatus := gateway.ListenerStatus{
+			Name:           listener.Name,
+			SupportedKinds: []gateway.RouteGroupKind{{Group: &routeGroup, Kind: gateway.HTTPRouteGVK.Kind}},
+			Conditions:     []metav1.Condition{},
+		}
+		for _, existing := range status.Listeners {
+			if existing.Name == listener.Name {
+				listenerStatus.Conditions = existing.Conditions
+			}
+		}
+		for _, ref := range routes {
+			if ref.SectionName == nil || *ref.SectionName == listener.Name {
+				listenerStatus.AttachedRoutes++
+			}
+		}
+		acceptedReason := "Accepted"
+		if !accepted {
+			acceptedReason = "Invalid"
+		}
+		setCondition(&listenerStatus.Conditions, "Accepted", accepted, acceptedReason, "")
+		setCondition(&listenerStatus.Conditions, "Programmed", programmed, programmedReason, programmedMessage)
+		setCondition(&listenerStatus.Conditions, "ResolvedRefs", true, "ResolvedRefs", "")
+		listeners = append(listeners, listenerStatus)
+	}
+	status.Listeners = listeners
+
+	err = patchStatusWithRetry(ctx, r.Client, obj, func(latest *unstructured.Unstructured) error {
+		return gateway.SetStatus(latest, status)
+	})
+	if err != nil {
+		logger.Error(err, "Failed to update Gateway status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// attachedRoutes returns the parentRefs of all HTTPRoutes pointing at the Gateway
+func (r *GatewayReconciler) attachedRoutes(ctx context.Context, key types.NamespacedName) ([]gateway.ParentReference, error) {
+	list := gateway.NewUnstructuredList(gateway.HTTPRouteGVK)
+	if err := r.List(ctx, list); err != nil {
+		return nil, err
+	}
+
+	var refs []gateway.ParentReference
+	for i := range list.Items {
+		route := &gateway.HTTPRoute{}
+		if err := gateway.FromUnstructured(&list.Items[i], route); err != nil {
+			continue
+		}
+		for _, ref := range route.Spec.ParentRefs {
+			if parent, ok := gateway.ParentGateway(route, ref); ok && parent == key {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs, nil
+}
+
+// gatewaysForGatewayClass maps a GatewayClass to the Gateways using it
+func (r *GatewayReconciler) gatewaysForGatewayClass(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := gateway.NewUnstructuredList(gateway.GatewayGVK)
+	if err := r.List(ctx, list); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list Gateways for GatewayClass", "gatewayClass", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range list.Items {
+		gw := &gateway.Gateway{}
+		if err := gateway.FromUnstructured(&list.Items[i], gw); err != nil || gw.Spec.GatewayClassName != obj.GetName() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+	}
+	return requests
+}
+
+// gatewaysForHTTPRoute maps an HTTPRoute to its parent Gateways to recount attached routes
+func (r *GatewayReconciler) gatewaysForHTTPRoute(_ context.Context, obj client.Object) []reconcile.Request {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	route := &gateway.HTTPRoute{}
+	if err := gateway.FromUnstructured(u, route); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, ref := range route.Spec.ParentRefs {
+		if parent, ok := gateway.ParentGateway(route, ref); ok {
+			requests = append(requests, reconcile.Request{NamespacedName: parent})
+		}
+	}
+	return requests
+}
+
+// gatewaysForClusterRelay maps a ClusterRelay to every Gateway, whose addresses may come from it
+func (r *GatewayReconciler) gatewaysForClusterRelay(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := gateway.NewUnstructuredList(gateway.GatewayGVK)
+	if err := r.List(ctx, list); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list Gateways for ClusterRelay", "clusterRelay", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for i := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GatewayReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(gateway.NewUnstructured(gateway.GatewayGVK)).
+		Watches(gateway.NewUnstructured(gateway.GatewayClassGVK),
+			handler.EnqueueRequestsFromMapFunc(r.gatewaysForGatewayClass)).
+		Watches(gateway.NewUnstructured(gateway.HTTPRouteGVK),
+			handler.EnqueueRequestsFromMapFunc(r.gatewaysForHTTPRoute)). // Recount attached routes
+		Watches(&portalv1alpha1.ClusterRelay{},
+			handler.EnqueueRequestsFromMapFunc(r.gatewaysForClusterRelay)).
+		Named("gateway").
+		Complete(r)
+}
+
+// getPortalGatewayClass returns the GatewayClass if it is handled by portal-expose, else nil
+func getPortalGatewayClass(ctx context.Context, c client.Client, name string) (*gateway.GatewayClass, error) {
+	obj := gateway.NewUnstructured(gateway.GatewayClassGVK)
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, obj); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	class := &gateway.GatewayClass{}
+	if err := gateway.FromUnstructured(obj, class); err != nil {
+		return nil, err
+	}
+	if class.Spec.ControllerName != gateway.ControllerName {
+		return nil, nil
+	}
+	return class, nil
+}
+
+// decodeStatus decodes the status of an unstructured Gateway API object
+func decodeStatus(obj *unstructured.Unstructured, status any) error {
+	content, ok := obj.Object["status"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(content, status)
+}
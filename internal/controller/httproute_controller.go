@@ -0,0 +1,304 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/gateway"
+)
+
+// HTTPRouteReconciler publishes HTTPRoutes attached to portal Gateways through generated PortalExposes
+type HTTPRouteReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// portalParent is a route parentRef pointing at a Gateway handled by portal-expose
+type portalParent struct {
+	Ref     gateway.ParentReference
+	Gateway *gateway.Gateway
+	Class   *gateway.GatewayClass
+}
+
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gatewayclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=portal.gosuda.org,resources=portalexposes,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile keeps the PortalExpose generated for an HTTPRoute and the route's parent status in sync
+func (r *HTTPRouteReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	obj := gateway.NewUnstructured(gateway.HTTPRouteGVK)
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		// The generated PortalExpose is garbage collected with the route
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	route := &gateway.HTTPRoute{}
+	if err := gateway.FromUnstructured(obj, route); err != nil {
+		logger.Error(err, "Failed to decode HTTPRoute")
+		return ctrl.Result{}, err
+	}
+
+	parents, err := r.portalParents(ctx, route)
+	if err != nil {
+		logger.Error(err, "Failed to resolve parent Gateways")
+		return ctrl.Result{}, err
+	}
+
+	// PortalExposes of earlier versions took the route name and could collide with a Service's or an Ingress's
+	if err := deleteLegacyPortalExpose(ctx, r.Client, obj); err != nil {
+		logger.Error(err, "Failed to delete the PortalExpose generated under the HTTPRoute name")
+		return ctrl.Result{}, err
+	}
+
+	existing := &portalv1alpha1.PortalExpose{}
+	err = r.Get(ctx, client.ObjectKey{Namespace: route.Namespace, Name: gateway.PortalExposeName(route)}, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to get PortalExpose")
+		return ctrl.Result{}, err
+	}
+	found := err == nil
+
+	// Not attached to a portal Gateway (anymore): drop what we generated
+	if len(parents) == 0 || !route.DeletionTimestamp.IsZero() {
+		if err := r.deleteGenerated(ctx, obj, existing, found); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.updateParentStatus(ctx, obj, nil, nil, nil)
+	}
+
+	// Routes attached to several portal Gateways publish on the relays of the first one
+	desired, err := r.buildPortalExpose(route, parents[0])
+	var conditionErr *gateway.ConditionError
+	if goerrors.As(err, &conditionErr) {
+		logger.Info("HTTPRoute cannot be published", "reason", conditionErr.Reason, "message", conditionErr.Message)
+		if err := r.deleteGenerated(ctx, obj, existing, found); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.updateParentStatus(ctx, obj, parents, nil, conditionErr)
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	switch {
+	case !found:
+		if err := controllerutil.SetControllerReference(obj, desired, r.Scheme); err != nil {
+			logger.Error(err, "Failed to set controller reference")
+			return ctrl.Result{}, err
+		}
+		logger.Info("Creating PortalExpose for HTTPRoute", "name", desired.Name)
+		if err := r.Create(ctx, desired); err != nil {
+			logger.Error(err, "Failed to create PortalExpose")
+			return ctrl.Result{}, err
+		}
+		existing = desired
+	case !metav1.IsControlledBy(existing, obj):
+		// Never take over a PortalExpose someone wrote by hand
+		return ctrl.Result{}, r.updateParentStatus(ctx, obj, parents, nil, &gateway.ConditionError{
+			Reason:  "PortalExposeExists",
+			Message: fmt.Sprintf("PortalExpose '%s' already exists and is not managed by this HTTPRoute", existing.Name),
+		})
+	case !equality.Semantic.DeepEqual(existing.Spec, desired.Spec):
+		logger.Info("Updating PortalExpose from HTTPRoute", "name", existing.Name)
+		existing.Spec = desired.Spec
+		if err := r.Update(ctx, existing); err != nil {
+			logger.Error(err, "Failed to update PortalExpose")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, r.updateParentStatus(ctx, obj, parents, existing, nil)
+}
+
+// portalParents returns the parentRefs of the route that point at portal Gateways
+func (r *HTTPRouteReconciler) portalParents(ctx context.Context, route *gateway.HTTPRoute) ([]portalParent, error) {
+	var parents []portalParent
+	for _, ref := range route.Spec.ParentRefs {
+		key, ok := gateway.ParentGateway(route, ref)
+		if !ok {
+			continue
+		}
+
+		obj := gateway.NewUnstructured(gateway.GatewayGVK)
+		if err := r.Get(ctx, key, obj); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		gw := &gateway.Gateway{}
+		if err := gateway.FromUnstructured(obj, gw); err != nil {
+			return nil, err
+		}
+
+		class, err := getPortalGatewayClass(ctx, r.Client, gw.Spec.GatewayClassName)
+		if err != nil {
+			return nil, err
+		}
+		if class != nil {
+			parents = append(parents, portalParent{Ref: ref, Gateway: gw, Class: class})
+		}
+	}
+	return parents, nil
+}
+
+// buildPortalExpose translates the route into the PortalExpose publishing it on the parent's relays
+func (r *HTTPRouteReconciler) buildPortalExpose(route *gateway.HTTPRoute, parent portalParent) (*portalv1alpha1.PortalExpose, error) {
+	targets, err := gateway.RelayTargets(parent.Gateway, parent.Class)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := gateway.Backend(route)
+	if err != nil {
+		return nil, err
+	}
+	return gateway.BuildPortalExpose(route, backend, targets)
+}
+
+// deleteGenerated deletes the PortalExpose generated for the route, leaving any other alone
+func (r *HTTPRouteReconciler) deleteGenerated(
+	ctx context.Context,
+	obj *unstructured.Unstructured,
+	existing *portalv1alpha1.PortalExpose,
+	found bool,
+) error {
+	if !found || !metav1.IsControlledBy(existing, obj) || !existing.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	log.FromContext(ctx).Info("Deleting PortalExpose of HTTPRoute", "name", existing.Name)
+	if err := r.Delete(ctx, existing); client.IgnoreNotFound(err) != nil {
+		log.FromContext(ctx).Error(err, "Failed to delete PortalExpose")
+		return err
+	}
+	return nil
+}
+
+// updateParentStatus writes the route status of every portal parent, keeping entries of other controllers
+func (r *HTTPRouteReconciler) updateParentStatus(
+	ctx context.Context,
+	obj *unstructured.Unstructured,
+	parents []portalParent,
+	portalExpose *portalv1alpha1.PortalExpose,
+	conditionErr *gateway.ConditionError,
+) error {
+	err := patchStatusWithRetry(ctx, r.Client, obj, func(latest *unstructured.Unstructured) error {
+		// Entries of other controllers are taken from the latest read, so their writes are never undone
+		route := &gateway.HTTPRoute{}
+		if err := gateway.FromUnstructured(latest, route); err != nil {
+			return err
+		}
+
+		statuses := make([]gateway.RouteParentStatus, 0, len(route.Status.Parents)+len(parents))
+		for _, status := range route.Status.Parents {
+			if status.ControllerName != gateway.ControllerName {
+				statuses = append(statuses, status)
+			}
+		}
+		for _, parent := range parents {
+			status := gateway.RouteParentStatus{ParentRef: parent.Ref, ControllerName: gateway.ControllerName}
+			for _, existing := range route.Status.Parents {
+				if existing.ControllerName == gateway.ControllerName && equality.Semantic.DeepEqual(existing.ParentRef, parent.Ref) {
+					status.Conditions = existing.Conditions
+				}
+			}
+
+			if conditionErr != nil {
+				gateway.SetErrorConditions(&status.Conditions, route.Generation, conditionErr)
+			} else {
+				gateway.SetRouteConditions(&status.Conditions, route.Generation, portalExpose)
+			}
+			statuses = append(statuses, status)
+		}
+		return gateway.SetStatus(latest, &gateway.HTTPRouteStatus{Parents: statuses})
+	})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to update HTTPRoute status")
+		return err
+	}
+	return nil
+}
+
+// httpRoutesForGateway maps a Gateway to the HTTPRoutes attached to it
+func (r *HTTPRouteReconciler) httpRoutesForGateway(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := gateway.NewUnstructuredList(gateway.HTTPRouteGVK)
+	if err := r.List(ctx, list); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list HTTPRoutes for Gateway", "gateway", obj.GetName())
+		return nil
+	}
+
+	key := client.ObjectKeyFromObject(obj)
+	var requests []reconcile.Request
+	for i := range list.Items {
+		route := &gateway.HTTPRoute{}
+		if err := gateway.FromUnstructured(&list.Items[i], route); err != nil {
+			continue
+		}
+		for _, ref := range route.Spec.ParentRefs {
+			if parent, ok := gateway.ParentGateway(route, ref); ok && parent == key {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// allHTTPRoutes maps a GatewayClass change to every HTTPRoute; GatewayClasses change rarely
+func (r *HTTPRouteReconciler) allHTTPRoutes(ctx context.Context, _ client.Object) []reconcile.Request {
+	list := gateway.NewUnstructuredList(gateway.HTTPRouteGVK)
+	if err := r.List(ctx, list); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list HTTPRoutes")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for i := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HTTPRouteReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(gateway.NewUnstructured(gateway.HTTPRouteGVK)).
+		Owns(&portalv1alpha1.PortalExpose{}). // Mirror PortalExpose status into the route status
+		Watches(gateway.NewUnstructured(gateway.GatewayGVK),
+			handler.EnqueueRequestsFromMapFunc(r.httpRoutesForGateway)).
+		Watches(gateway.NewUnstructured(gateway.GatewayClassGVK),
+			handler.EnqueueRequestsFromMapFunc(r.allHTTPRoutes)).
+		Named("httproute").
+		Complete(r)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/clusterrelay"
+	"github.com/gosuda/portal-expose/internal/tunnel"
+)
+
+const (
+	// clusterRelayRefIndex indexes PortalExposes by the ClusterRelays they reference
+	clusterRelayRefIndex = "spec.relay.targets.relayRef.name"
+
+	// subdomainIndex indexes PortalExposes by the subdomains of spec.app and spec.endpoints
+	subdomainIndex = "subdomains"
+
+	// serviceNameIndex indexes PortalExposes by the Services of spec.app and spec.endpoints
+	serviceNameIndex = "spec.app.service.name"
+
+	// tunnelClassNameIndex indexes PortalExposes by spec.tunnelClassName
+	// PortalExposes using the default class are indexed under ""
+	tunnelClassNameIndex = "spec.tunnelClassName"
+
+	// credentialsSecretIndex indexes PortalExposes by the Secrets holding their relay credentials
+	credentialsSecretIndex = "spec.relay.targets.credentialsSecretRef.name"
+)
+
+// SetupIndexes registers the field indexes shared by the controllers.
+// It must be called once, before the controllers are set up with the Manager.
+func SetupIndexes(ctx context.Context, mgr ctrl.Manager) error {
+	indexer := mgr.GetFieldIndexer()
+
+	if err := indexer.IndexField(ctx, &portalv1alpha1.PortalExpose{}, clusterRelayRefIndex,
+		func(obj client.Object) []string {
+			return clusterrelay.ReferencedNames(obj.(*portalv1alpha1.PortalExpose))
+		}); err != nil {
+		return err
+	}
+
+	if err := indexer.IndexField(ctx, &portalv1alpha1.PortalExpose{}, subdomainIndex,
+		func(obj client.Object) []string {
+			return tunnel.Subdomains(obj.(*portalv1alpha1.PortalExpose))
+		}); err != nil {
+		return err
+	}
+
+	if err := indexer.IndexField(ctx, &portalv1alpha1.PortalExpose{}, serviceNameIndex,
+		func(obj client.Object) []string {
+			return serviceNames(obj.(*portalv1alpha1.PortalExpose))
+		}); err != nil {
+		return err
+	}
+
+	if err := indexer.IndexField(ctx, &portalv1alpha1.PortalExpose{}, tunnelClassNameIndex,
+		func(obj client.Object) []string {
+			return []string{obj.(*portalv1alpha1.PortalExpose).Spec.TunnelClassName}
+		}); err != nil {
+		return err
+	}
+
+	return indexer.IndexField(ctx, &portalv1alpha1.PortalExpose{}, credentialsSecretIndex,
+		func(obj client.Object) []string {
+			return credentialsSecretNames(obj.(*portalv1alpha1.PortalExpose))
+		})
+}
+
+// serviceNames returns the distinct Services exposed by a PortalExpose
+func serviceNames(portalExpose *portalv1alpha1.PortalExpose) []string {
+	var names []string
+	for _, endpoint := range tunnel.AppEndpoints(portalExpose) {
+		if !slices.Contains(names, endpoint.Service.Name) {
+			names = append(names, endpoint.Service.Name)
+		}
+	}
+	return names
+}
+
+// credentialsSecretNames returns the distinct relay credentials Secrets referenced by a PortalExpose
+func credentialsSecretNames(portalExpose *portalv1alpha1.PortalExpose) []string {
+	var names []string
+	for _, target := range portalExpose.Spec.Relay.Targets {
+		if target.CredentialsSecretRef != nil && !slices.Contains(names, target.CredentialsSecretRef.Name) {
+			names = append(names, target.CredentialsSecretRef.Name)
+		}
+	}
+	return names
+}
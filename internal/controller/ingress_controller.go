@@ -0,0 +1,229 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/autoexpose"
+	"github.com/gosuda/portal-expose/internal/util"
+)
+
+// IngressReconciler publishes Ingresses of a portal IngressClass through generated PortalExposes
+type IngressReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingressclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=portal.gosuda.org,resources=portalexposes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile keeps the PortalExpose generated for an Ingress and the Ingress load balancer status in sync
+func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	ingress := &networkingv1.Ingress{}
+	if err := r.Get(ctx, req.NamespacedName, ingress); err != nil {
+		// The generated PortalExpose is garbage collected with the Ingress
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	class, err := r.portalIngressClass(ctx, ingress)
+	if err != nil {
+		logger.Error(err, "Failed to resolve IngressClass")
+		return ctrl.Result{}, err
+	}
+
+	// PortalExposes of earlier versions took the Ingress name and could collide with a Service's
+	if err := deleteLegacyPortalExpose(ctx, r.Client, ingress); err != nil {
+		logger.Error(err, "Failed to delete the PortalExpose generated under the Ingress name")
+		return ctrl.Result{}, err
+	}
+
+	existing := &portalv1alpha1.PortalExpose{}
+	err = r.Get(ctx, client.ObjectKey{Namespace: ingress.Namespace, Name: autoexpose.IngressPortalExposeName(ingress)}, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to get PortalExpose")
+		return ctrl.Result{}, err
+	}
+	found := err == nil
+
+	// Not of a portal IngressClass (anymore): drop what we generated, leave any other alone
+	if class == nil || !ingress.DeletionTimestamp.IsZero() {
+		if found && metav1.IsControlledBy(existing, ingress) && existing.DeletionTimestamp.IsZero() {
+			logger.Info("Deleting PortalExpose of Ingress no longer of a portal IngressClass", "name", existing.Name)
+			if err := r.Delete(ctx, existing); client.IgnoreNotFound(err) != nil {
+				logger.Error(err, "Failed to delete PortalExpose")
+				return ctrl.Result{}, err
+			}
+			r.Recorder.Event(ingress, corev1.EventTypeNormal, util.EventReasonUnexposed,
+				fmt.Sprintf("Deleted PortalExpose '%s'", existing.Name))
+			return ctrl.Result{}, r.updateLoadBalancer(ctx, ingress, networkingv1.IngressLoadBalancerStatus{})
+		}
+		return ctrl.Result{}, nil
+	}
+
+	targets, err := autoexpose.IngressRelayTargets(ingress, class)
+	if err != nil {
+		logger.Info("Invalid Ingress", "error", err.Error())
+		r.Recorder.Event(ingress, corev1.EventTypeWarning, util.EventReasonInvalidIngress, err.Error())
+		return ctrl.Result{}, nil // Wait for the Ingress or its class to change
+	}
+	desired, err := autoexpose.BuildIngressPortalExpose(ingress, targets)
+	if err != nil {
+		logger.Info("Invalid Ingress", "error", err.Error())
+		r.Recorder.Event(ingress, corev1.EventTypeWarning, util.EventReasonInvalidIngress, err.Error())
+		return ctrl.Result{}, nil
+	}
+
+	switch {
+	case !found:
+		if err := controllerutil.SetControllerReference(ingress, desired, r.Scheme); err != nil {
+			logger.Error(err, "Failed to set controller reference")
+			return ctrl.Result{}, err
+		}
+		logger.Info("Creating PortalExpose for Ingress", "name", desired.Name)
+		if err := r.Create(ctx, desired); err != nil {
+			logger.Error(err, "Failed to create PortalExpose")
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Event(ingress, corev1.EventTypeNormal, util.EventReasonExposed,
+			fmt.Sprintf("Created PortalExpose '%s'", desired.Name))
+		return ctrl.Result{}, nil // The load balancer status follows once the PortalExpose reports a URL
+	case !metav1.IsControlledBy(existing, ingress):
+		// Never take over a PortalExpose someone wrote by hand
+		r.Recorder.Event(ingress, corev1.EventTypeWarning, util.EventReasonPortalExposeExists,
+			fmt.Sprintf("PortalExpose '%s' already exists and is not managed by this Ingress", existing.Name))
+		return ctrl.Result{}, nil
+	case !equality.Semantic.DeepEqual(existing.Spec, desired.Spec):
+		logger.Info("Updating PortalExpose from Ingress", "name", existing.Name)
+		existing.Spec = desired.Spec
+		if err := r.Update(ctx, existing); err != nil {
+			logger.Error(err, "Failed to update PortalExpose")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, r.updateLoadBalancer(ctx, ingress, autoexpose.IngressLoadBalancer(existing))
+}
+
+// deleteLegacyPortalExpose deletes the PortalExpose an earlier controller version generated for owner under
+// the owner's own name, once the owner generates it under a suffixed name
+func deleteLegacyPortalExpose(ctx context.Context, c client.Client, owner client.Object) error {
+	legacy := &portalv1alpha1.PortalExpose{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(owner), legacy); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(legacy, owner) || !legacy.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	log.FromContext(ctx).Info("Deleting PortalExpose generated under the previous name", "name", legacy.Name)
+	return client.IgnoreNotFound(c.Delete(ctx, legacy))
+}
+
+// portalIngressClass returns the IngressClass of the Ingress if portal-expose handles it, or nil
+// Ingresses without a class use the IngressClass annotated as the cluster default
+func (r *IngressReconciler) portalIngressClass(ctx context.Context, ingress *networkingv1.Ingress) (*networkingv1.IngressClass, error) {
+	if name := autoexpose.IngressClassName(ingress); name != "" {
+		class := &networkingv1.IngressClass{}
+		if err := r.Get(ctx, client.ObjectKey{Name: name}, class); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+		if !autoexpose.IsPortalIngressClass(class) {
+			return nil, nil
+		}
+		return class, nil
+	}
+
+	classes := &networkingv1.IngressClassList{}
+	if err := r.List(ctx, classes); err != nil {
+		return nil, err
+	}
+	for i := range classes.Items {
+		class := &classes.Items[i]
+		if class.Annotations[networkingv1.AnnotationIsDefaultIngressClass] == "true" && autoexpose.IsPortalIngressClass(class) {
+			return class, nil
+		}
+	}
+	return nil, nil
+}
+
+// updateLoadBalancer writes the Ingress load balancer status if it changed
+func (r *IngressReconciler) updateLoadBalancer(
+	ctx context.Context,
+	ingress *networkingv1.Ingress,
+	status networkingv1.IngressLoadBalancerStatus,
+) error {
+	if equality.Semantic.DeepEqual(ingress.Status.LoadBalancer, status) {
+		return nil
+	}
+	err := patchStatusWithRetry(ctx, r.Client, ingress, func(latest *networkingv1.Ingress) error {
+		latest.Status.LoadBalancer = status
+		return nil
+	})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to update Ingress status")
+		return err
+	}
+	return nil
+}
+
+// allIngresses maps an IngressClass change to every Ingress; IngressClasses change rarely
+func (r *IngressReconciler) allIngresses(ctx context.Context, _ client.Object) []reconcile.Request {
+	list := &networkingv1.IngressList{}
+	if err := r.List(ctx, list); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list Ingresses")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for i := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&networkingv1.Ingress{}).
+		Owns(&portalv1alpha1.PortalExpose{}). // Mirror the public URLs into the Ingress status
+		Watches(&networkingv1.IngressClass{},
+			handler.EnqueueRequestsFromMapFunc(r.allIngresses)).
+		Named("ingress").
+		Complete(r)
+}
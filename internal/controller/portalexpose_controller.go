@@ -18,39 +18,85 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	goerrors "errors"
 	"fmt"
+	"strings"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/csaupgrade"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/clusterrelay"
 	"github.com/gosuda/portal-expose/internal/tunnel"
 	"github.com/gosuda/portal-expose/internal/tunnelclass"
 	"github.com/gosuda/portal-expose/internal/util"
 )
 
+// relayStatusResyncInterval is how often relay session state is re-read from tunnel pods
+const relayStatusResyncInterval = 30 * time.Second
+
+// fieldManager is the server-side apply field manager of the tunnel Deployments
+const fieldManager = "portal-expose-controller"
+
+// legacyFieldManager is the manager the API server recorded for the Create and Update calls of
+// controller versions that did not apply Deployments, derived from the default user agent
+var legacyFieldManager = strings.SplitN(rest.DefaultKubernetesUserAgent(), "/", 2)[0]
+
 // PortalExposeReconciler reconciles a PortalExpose object
 type PortalExposeReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+
+	// StatusClient reads relay session state from tunnel pods
+	// Defaults to tunnel.NewHTTPStatusClient() when nil
+	StatusClient tunnel.StatusClient
+
+	// Sizes maps TunnelClass size tiers to container resources
+	// Defaults to tunnel.DefaultSizes() when nil
+	Sizes tunnel.SizeTable
+
+	// APIReader reads Secrets the cache does not hold yet, see CacheByObject
+	// Defaults to the Client when nil
+	APIReader client.Reader
 }
 
 // +kubebuilder:rbac:groups=portal.gosuda.org,resources=portalexposes,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=portal.gosuda.org,resources=portalexposes/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=portal.gosuda.org,resources=portalexposes/finalizers,verbs=update
 // +kubebuilder:rbac:groups=portal.gosuda.org,resources=tunnelclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=portal.gosuda.org,resources=clusterrelays,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -87,56 +133,194 @@ func (r *PortalExposeReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	// 3. Validate referenced Service exists
-	service := &corev1.Service{}
-	serviceKey := types.NamespacedName{
-		Name:      portalExpose.Spec.App.Service.Name,
-		Namespace: portalExpose.Namespace,
-	}
-	if err := r.Get(ctx, serviceKey, service); err != nil {
-		if errors.IsNotFound(err) {
-			logger.Info("Service not found", "service", portalExpose.Spec.App.Service.Name)
-			portalExpose.Status.Phase = util.PhaseFailed
-			util.SetCondition(&portalExpose.Status.Conditions, util.ConditionServiceExists, metav1.ConditionFalse,
-				"ServiceNotFound", fmt.Sprintf("Service '%s' not found in namespace '%s'", portalExpose.Spec.App.Service.Name, portalExpose.Namespace))
-			util.SetCondition(&portalExpose.Status.Conditions, util.ConditionAvailable, metav1.ConditionFalse,
-				"ServiceNotFound", "PortalExpose failed due to missing Service")
-
-			r.Recorder.Event(portalExpose, corev1.EventTypeWarning, "ServiceNotFound",
-				fmt.Sprintf("Referenced Service '%s' not found", portalExpose.Spec.App.Service.Name))
+	// 3. Validate referenced Services exist and resolve every endpoint's Service port
+	endpoints := tunnel.AppEndpoints(portalExpose)
+	for i := range endpoints {
+		endpoint := &endpoints[i]
+		service := &corev1.Service{}
+		serviceKey := types.NamespacedName{
+			Name:      endpoint.Service.Name,
+			Namespace: portalExpose.Namespace,
+		}
+		if err := r.Get(ctx, serviceKey, service); err != nil {
+			if errors.IsNotFound(err) {
+				logger.Info("Service not found", "service", endpoint.Service.Name, "endpoint", endpoint.Name)
+				portalExpose.Status.Phase = util.PhaseFailed
+				util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionServiceExists, metav1.ConditionFalse,
+					"ServiceNotFound", fmt.Sprintf("Service '%s' not found in namespace '%s'", endpoint.Service.Name, portalExpose.Namespace))
+				util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionAvailable, metav1.ConditionFalse,
+					"ServiceNotFound", "PortalExpose failed due to missing Service")
+
+				if err := r.patchStatus(ctx, portalExpose); err != nil {
+					logger.Error(err, "Failed to update status")
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{}, nil // Don't requeue, wait for Service creation event
+			}
+			logger.Error(err, "Failed to get Service")
+			return ctrl.Result{}, err
+		}
 
-			if err := r.Status().Update(ctx, portalExpose); err != nil {
+		servicePort, err := tunnel.ResolveServicePort(service, endpoint.Service.Port)
+		if err != nil {
+			logger.Info("Service port not found", "service", service.Name, "port", endpoint.Service.Port.String(), "endpoint", endpoint.Name)
+			portalExpose.Status.Phase = util.PhaseFailed
+			util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionServiceExists, metav1.ConditionTrue,
+				"ServiceFound", "Service exists")
+			util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionServicePortResolved, metav1.ConditionFalse,
+				"ServicePortNotFound", fmt.Sprintf("Endpoint '%s': %s", endpoint.Name, err.Error()))
+			util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionAvailable, metav1.ConditionFalse,
+				"ServicePortNotFound", "PortalExpose failed due to missing Service port")
+
+			if err := r.patchStatus(ctx, portalExpose); err != nil {
 				logger.Error(err, "Failed to update status")
 				return ctrl.Result{}, err
 			}
-			return ctrl.Result{}, nil // Don't requeue, wait for Service creation event
+			return ctrl.Result{}, nil // Don't requeue, wait for the Service ports to change
 		}
-		logger.Error(err, "Failed to get Service")
-		return ctrl.Result{}, err
+		endpoint.ServicePort = servicePort
+		endpoint.ServiceSelector = service.Spec.Selector
+		endpoint.TargetPort = tunnel.ServiceTargetPort(service, servicePort)
 	}
 
-	util.SetCondition(&portalExpose.Status.Conditions, util.ConditionServiceExists, metav1.ConditionTrue,
+	util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionServiceExists, metav1.ConditionTrue,
 		"ServiceFound", "Service exists")
+	util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionServicePortResolved, metav1.ConditionTrue,
+		"ServicePortResolved", fmt.Sprintf("Resolved the Service ports of %d endpoints", len(endpoints)))
 
 	// 4. Resolve TunnelClass
 	tunnelClass, err := r.resolveTunnelClass(ctx, portalExpose)
 	if err != nil {
-		logger.Error(err, "Failed to resolve TunnelClass")
+		var reason string
+		switch {
+		case goerrors.Is(err, tunnelclass.ErrNamespaceNotAllowed):
+			reason = "NamespaceNotAllowed"
+		case errors.IsNotFound(err), goerrors.Is(err, tunnelclass.ErrNoDefaultTunnelClass):
+			reason = "TunnelClassNotFound"
+		default:
+			// Transient API errors are retried instead of failing the PortalExpose
+			logger.Error(err, "Failed to resolve TunnelClass")
+			return ctrl.Result{}, err
+		}
+		logger.Info("TunnelClass cannot be used", "reason", reason, "error", err.Error())
 		portalExpose.Status.Phase = util.PhaseFailed
-		util.SetCondition(&portalExpose.Status.Conditions, "TunnelClassExists", metav1.ConditionFalse,
-			"TunnelClassNotFound", err.Error())
+		util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, "TunnelClassExists", metav1.ConditionFalse,
+			reason, err.Error())
+
+		if statusErr := r.patchStatus(ctx, portalExpose); statusErr != nil {
+			logger.Error(statusErr, "Failed to update status")
+			return ctrl.Result{}, statusErr
+		}
+		return ctrl.Result{}, nil
+	}
 
-		r.Recorder.Event(portalExpose, corev1.EventTypeWarning, "TunnelClassNotFound", err.Error())
+	// The TunnelClass size must name a known tier; the existing tunnel keeps running until it is fixed
+	resources, err := r.sizes().Resources(tunnelClass)
+	if err != nil {
+		logger.Info("TunnelClass has an invalid size", "tunnelClass", tunnelClass.Name, "error", err.Error())
+		portalExpose.Status.Phase = util.PhaseFailed
+		util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionAvailable, metav1.ConditionFalse,
+			"InvalidSize", fmt.Sprintf("TunnelClass '%s': %s", tunnelClass.Name, err.Error()))
 
-		if statusErr := r.Status().Update(ctx, portalExpose); statusErr != nil {
+		if statusErr := r.patchStatus(ctx, portalExpose); statusErr != nil {
 			logger.Error(statusErr, "Failed to update status")
 			return ctrl.Result{}, statusErr
 		}
 		return ctrl.Result{}, nil
 	}
 
-	// 5. Generate desired Deployment spec
-	desiredDeployment := tunnel.BuildDeployment(portalExpose, tunnelClass)
+	// 5. Resolve relay targets (inline URLs or ClusterRelay references)
+	relays, err := clusterrelay.ResolveTargets(ctx, r.Client, portalExpose.Spec.Relay.Targets)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Error(err, "Failed to resolve relay targets")
+			return ctrl.Result{}, err
+		}
+		logger.Info("ClusterRelay not found", "error", err.Error())
+		portalExpose.Status.Phase = util.PhaseFailed
+		util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionRelaysResolved, metav1.ConditionFalse,
+			"ClusterRelayNotFound", err.Error())
+
+		if statusErr := r.patchStatus(ctx, portalExpose); statusErr != nil {
+			logger.Error(statusErr, "Failed to update status")
+			return ctrl.Result{}, statusErr
+		}
+		return ctrl.Result{}, nil // Wait for ClusterRelay creation event
+	}
+
+	util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionRelaysResolved, metav1.ConditionTrue,
+		"RelaysResolved", fmt.Sprintf("Resolved %d relay endpoints", len(relays)))
+
+	// Env-mounted credentials are told apart by their variable prefix only
+	if conflicts := tunnel.CredentialsEnvPrefixConflicts(portalExpose.Spec.Relay.Targets); len(conflicts) > 0 {
+		target := portalExpose.Spec.Relay.Targets[conflicts[0]]
+		message := fmt.Sprintf("Relay target '%s' credentials variable prefix %s overlaps another Env-mounted relay target",
+			target.Name, tunnel.CredentialsEnvPrefix(target.Name))
+		logger.Info("Relay credentials variable prefixes overlap", "target", target.Name)
+		portalExpose.Status.Phase = util.PhaseFailed
+		util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionAvailable, metav1.ConditionFalse,
+			"CredentialsEnvPrefixConflict", message)
+
+		if statusErr := r.patchStatus(ctx, portalExpose); statusErr != nil {
+			logger.Error(statusErr, "Failed to update status")
+			return ctrl.Result{}, statusErr
+		}
+		return ctrl.Result{}, nil // Wait for a spec change
+	}
+
+	// Relay credentials must exist before tunnel pods can mount them
+	var credentialSecrets []*corev1.Secret
+	for _, name := range tunnel.CredentialsSecretNames(relays) {
+		secret := &corev1.Secret{}
+		if err := r.getTunnelSecret(ctx, types.NamespacedName{Name: name, Namespace: portalExpose.Namespace}, secret); err != nil {
+			if !errors.IsNotFound(err) {
+				logger.Error(err, "Failed to get relay credentials Secret")
+				return ctrl.Result{}, err
+			}
+			message := fmt.Sprintf("Relay credentials Secret '%s' not found in namespace '%s'", name, portalExpose.Namespace)
+			logger.Info("Relay credentials Secret not found", "secret", name)
+			portalExpose.Status.Phase = util.PhaseFailed
+			util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionCredentialsMissing, metav1.ConditionTrue,
+				"SecretNotFound", message)
+			util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionAvailable, metav1.ConditionFalse,
+				"CredentialsMissing", "PortalExpose failed due to missing relay credentials")
+
+			if statusErr := r.patchStatus(ctx, portalExpose); statusErr != nil {
+				logger.Error(statusErr, "Failed to update status")
+				return ctrl.Result{}, statusErr
+			}
+			return ctrl.Result{}, nil // Wait for Secret creation event
+		}
+		credentialSecrets = append(credentialSecrets, secret)
+	}
+	util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionCredentialsMissing, metav1.ConditionFalse,
+		"CredentialsFound", fmt.Sprintf("Found %d relay credentials Secrets", len(credentialSecrets)))
+
+	// 6. Ensure no older PortalExpose publishes the same app name on a shared relay domain
+	conflict, err := r.findNameConflict(ctx, portalExpose, relays)
+	if err != nil {
+		logger.Error(err, "Failed to check for app name conflicts")
+		return ctrl.Result{}, err
+	}
+	if conflict != nil {
+		return r.handleNameConflict(ctx, portalExpose, conflict)
+	}
+
+	util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionNameConflict, metav1.ConditionFalse,
+		"NameAvailable", "No subdomain is claimed by another PortalExpose")
+
+	// Tunnel identity, kept stable across pod restarts
+	fingerprint, err := r.reconcileIdentity(ctx, portalExpose)
+	if err != nil {
+		logger.Error(err, "Failed to reconcile tunnel identity")
+		return ctrl.Result{}, err
+	}
+	portalExpose.Status.IdentityFingerprint = fingerprint
+
+	// 7. Generate desired Deployment spec
+	desiredDeployment := tunnel.BuildDeployment(portalExpose, tunnelClass, relays, endpoints, resources)
+	tunnel.SetCredentialsHash(&desiredDeployment.Spec.Template, tunnel.CredentialsHash(credentialSecrets))
+	tunnel.SetIdentityFingerprint(&desiredDeployment.Spec.Template, fingerprint)
 
 	// Set PortalExpose as owner of the Deployment
 	if err := controllerutil.SetControllerReference(portalExpose, desiredDeployment, r.Scheme); err != nil {
@@ -144,65 +328,325 @@ func (r *PortalExposeReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, err
 	}
 
-	// 6. Reconcile Deployment
+	// 8. Reconcile PodDisruptionBudget, so node drains keep part of the tunnel up
+	if err := r.reconcilePodDisruptionBudget(ctx, portalExpose, tunnelClass); err != nil {
+		logger.Error(err, "Failed to reconcile PodDisruptionBudget")
+		return ctrl.Result{}, err
+	}
+
+	// 9. Reconcile HorizontalPodAutoscaler, removing it when the TunnelClass stops autoscaling
+	if err := r.reconcileHorizontalPodAutoscaler(ctx, portalExpose, tunnelClass); err != nil {
+		logger.Error(err, "Failed to reconcile HorizontalPodAutoscaler")
+		return ctrl.Result{}, err
+	}
+
+	// 10. Reconcile NetworkPolicy, removing it when the TunnelClass disables it
+	if err := r.reconcileNetworkPolicy(ctx, portalExpose, tunnelClass, relays, endpoints); err != nil {
+		logger.Error(err, "Failed to reconcile NetworkPolicy")
+		return ctrl.Result{}, err
+	}
+
+	// 11. Apply Deployment
+	// Server-side apply converges every field the controller sets and leaves fields
+	// owned by other managers alone, such as HPA replicas or injected sidecars
 	existingDeployment := &appsv1.Deployment{}
 	deploymentKey := types.NamespacedName{
 		Name:      desiredDeployment.Name,
 		Namespace: desiredDeployment.Namespace,
 	}
 	err = r.Get(ctx, deploymentKey, existingDeployment)
-	if err != nil && errors.IsNotFound(err) {
-		// Deployment doesn't exist, create it
-		logger.Info("Creating tunnel Deployment", "name", desiredDeployment.Name)
-		if err := r.Create(ctx, desiredDeployment); err != nil {
-			logger.Error(err, "Failed to create Deployment")
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to get Deployment")
+		return ctrl.Result{}, err
+	}
+	created := errors.IsNotFound(err)
+
+	// Fields written by Update calls of earlier controller versions would stay co-owned and never be
+	// pruned, so hand them over to the apply configuration first
+	if !created {
+		if err := r.upgradeManagedFields(ctx, existingDeployment); err != nil {
+			if errors.IsConflict(err) {
+				return ctrl.Result{Requeue: true}, nil // Retried against the latest Deployment
+			}
+			logger.Error(err, "Failed to upgrade Deployment managed fields")
 			return ctrl.Result{}, err
 		}
+	}
 
+	// The HPA owns spec.replicas of autoscaled Deployments once it scales them
+	// Until then keep applying the current count, so releasing the field does not reset it
+	if !created && tunnelClass.Spec.Autoscaling != nil {
+		if appliesField(existingDeployment, "spec", "replicas") {
+			desiredDeployment.Spec.Replicas = existingDeployment.Spec.Replicas
+			// The count may be stale; a scale since it was read fails the apply with a conflict
+			desiredDeployment.ResourceVersion = existingDeployment.ResourceVersion
+		} else {
+			desiredDeployment.Spec.Replicas = nil
+		}
+	}
+
+	if err := r.Patch(ctx, desiredDeployment, client.Apply,
+		client.FieldOwner(fieldManager), client.ForceOwnership); err != nil {
+		if errors.IsConflict(err) {
+			logger.V(1).Info("Deployment changed since it was read, retrying", "name", desiredDeployment.Name)
+			return ctrl.Result{Requeue: true}, nil
+		}
+		logger.Error(err, "Failed to apply Deployment")
+		return ctrl.Result{}, err
+	}
+
+	if created {
+		logger.Info("Created tunnel Deployment", "name", desiredDeployment.Name)
 		portalExpose.Status.Phase = util.PhasePending
-		util.SetCondition(&portalExpose.Status.Conditions, util.ConditionProgressing, metav1.ConditionTrue,
+		util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionProgressing, metav1.ConditionTrue,
 			"DeploymentCreated", "Tunnel Deployment created, waiting for pods")
 
-		r.Recorder.Event(portalExpose, corev1.EventTypeNormal, "Created",
+		r.Recorder.Event(portalExpose, corev1.EventTypeNormal, util.EventReasonCreated,
 			"PortalExpose created, deploying tunnel pods")
 
 		// Construct public URL
-		primaryRelayURL := portalExpose.Spec.Relay.Targets[0].URL
-		portalExpose.Status.PublicURL = tunnel.ConstructPublicURL(portalExpose.Spec.App.Name, primaryRelayURL)
+		portalExpose.Status.PublicURL = relays[0].PublicURL(portalExpose.Spec.App.Name)
 
-		if err := r.Status().Update(ctx, portalExpose); err != nil {
+		if err := r.patchStatus(ctx, portalExpose); err != nil {
 			logger.Error(err, "Failed to update status")
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{Requeue: true}, nil // Requeue to check pod readiness
-	} else if err != nil {
-		logger.Error(err, "Failed to get Deployment")
-		return ctrl.Result{}, err
 	}
 
-	// Deployment exists, check if update needed
-	// For MVP, we'll do a simple comparison of spec fields
-	// In production, use a more sophisticated comparison
-	if !deploymentSpecEqual(existingDeployment, desiredDeployment) {
-		logger.Info("Updating tunnel Deployment", "name", existingDeployment.Name)
-		existingDeployment.Spec = desiredDeployment.Spec
-		if err := r.Update(ctx, existingDeployment); err != nil {
-			logger.Error(err, "Failed to update Deployment")
-			return ctrl.Result{}, err
-		}
-
-		util.SetCondition(&portalExpose.Status.Conditions, util.ConditionProgressing, metav1.ConditionTrue,
+	// A new generation means the apply changed the spec and a rollout has started
+	if desiredDeployment.Generation != existingDeployment.Generation {
+		logger.Info("Updated tunnel Deployment", "name", desiredDeployment.Name)
+		util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionProgressing, metav1.ConditionTrue,
 			"DeploymentUpdating", "Rolling update in progress")
 
-		if err := r.Status().Update(ctx, portalExpose); err != nil {
+		if err := r.patchStatus(ctx, portalExpose); err != nil {
 			logger.Error(err, "Failed to update status")
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	// 7. Update status from Deployment and emit events
-	return r.updateStatusFromDeployment(ctx, portalExpose, existingDeployment, tunnelClass)
+	// 12. Update status from Deployment and emit events
+	return r.updateStatusFromDeployment(ctx, portalExpose, desiredDeployment, tunnelClass, relays, endpoints)
+}
+
+// reconcilePodDisruptionBudget creates or updates the PodDisruptionBudget of the tunnel Deployment
+func (r *PortalExposeReconciler) reconcilePodDisruptionBudget(
+	ctx context.Context,
+	portalExpose *portalv1alpha1.PortalExpose,
+	tunnelClass *portalv1alpha1.TunnelClass,
+) error {
+	logger := log.FromContext(ctx)
+
+	desired := tunnel.BuildPodDisruptionBudget(portalExpose, tunnelClass)
+	if err := controllerutil.SetControllerReference(portalExpose, desired, r.Scheme); err != nil {
+		return err
+	}
+
+	existing := &policyv1.PodDisruptionBudget{}
+	err := r.Get(ctx, client.ObjectKeyFromObject(desired), existing)
+	if errors.IsNotFound(err) {
+		logger.Info("Creating tunnel PodDisruptionBudget", "name", desired.Name)
+		return r.Create(ctx, desired)
+	} else if err != nil {
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(existing.Spec, desired.Spec) {
+		return nil
+	}
+	logger.Info("Updating tunnel PodDisruptionBudget", "name", existing.Name)
+	existing.Spec = desired.Spec
+	return r.Update(ctx, existing)
+}
+
+// reconcileHorizontalPodAutoscaler creates, updates or deletes the HorizontalPodAutoscaler of the tunnel Deployment
+func (r *PortalExposeReconciler) reconcileHorizontalPodAutoscaler(
+	ctx context.Context,
+	portalExpose *portalv1alpha1.PortalExpose,
+	tunnelClass *portalv1alpha1.TunnelClass,
+) error {
+	logger := log.FromContext(ctx)
+
+	key := types.NamespacedName{Name: portalExpose.Name + "-tunnel", Namespace: portalExpose.Namespace}
+	existing := &autoscalingv2.HorizontalPodAutoscaler{}
+	err := r.Get(ctx, key, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+
+	desired := tunnel.BuildHorizontalPodAutoscaler(portalExpose, tunnelClass)
+	if desired == nil {
+		if !found {
+			return nil
+		}
+		logger.Info("Deleting tunnel HorizontalPodAutoscaler", "name", existing.Name)
+		return client.IgnoreNotFound(r.Delete(ctx, existing))
+	}
+	if err := controllerutil.SetControllerReference(portalExpose, desired, r.Scheme); err != nil {
+		return err
+	}
+
+	if !found {
+		logger.Info("Creating tunnel HorizontalPodAutoscaler", "name", desired.Name)
+		return r.Create(ctx, desired)
+	}
+
+	if equality.Semantic.DeepEqual(existing.Spec.ScaleTargetRef, desired.Spec.ScaleTargetRef) &&
+		equality.Semantic.DeepEqual(existing.Spec.MinReplicas, desired.Spec.MinReplicas) &&
+		existing.Spec.MaxReplicas == desired.Spec.MaxReplicas &&
+		equality.Semantic.DeepEqual(existing.Spec.Metrics, desired.Spec.Metrics) {
+		return nil
+	}
+	logger.Info("Updating tunnel HorizontalPodAutoscaler", "name", existing.Name)
+	existing.Spec.ScaleTargetRef = desired.Spec.ScaleTargetRef
+	existing.Spec.MinReplicas = desired.Spec.MinReplicas
+	existing.Spec.MaxReplicas = desired.Spec.MaxReplicas
+	existing.Spec.Metrics = desired.Spec.Metrics
+	return r.Update(ctx, existing)
+}
+
+// reconcileNetworkPolicy creates, updates or deletes the NetworkPolicy of the tunnel pods
+func (r *PortalExposeReconciler) reconcileNetworkPolicy(
+	ctx context.Context,
+	portalExpose *portalv1alpha1.PortalExpose,
+	tunnelClass *portalv1alpha1.TunnelClass,
+	relays []tunnel.RelayEndpoint,
+	endpoints []tunnel.AppEndpoint,
+) error {
+	logger := log.FromContext(ctx)
+
+	key := types.NamespacedName{Name: portalExpose.Name + "-tunnel", Namespace: portalExpose.Namespace}
+	existing := &networkingv1.NetworkPolicy{}
+	err := r.Get(ctx, key, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+
+	desired, err := tunnel.BuildNetworkPolicy(portalExpose, tunnelClass, relays, endpoints)
+	if err != nil {
+		return err
+	}
+	if desired == nil {
+		if !found {
+			return nil
+		}
+		logger.Info("Deleting tunnel NetworkPolicy", "name", existing.Name)
+		return client.IgnoreNotFound(r.Delete(ctx, existing))
+	}
+	if err := controllerutil.SetControllerReference(portalExpose, desired, r.Scheme); err != nil {
+		return err
+	}
+
+	if !found {
+		logger.Info("Creating tunnel NetworkPolicy", "name", desired.Name)
+		return r.Create(ctx, desired)
+	}
+
+	if equality.Semantic.DeepEqual(existing.Spec, desired.Spec) {
+		return nil
+	}
+	logger.Info("Updating tunnel NetworkPolicy", "name", existing.Name)
+	existing.Spec = desired.Spec
+	return r.Update(ctx, existing)
+}
+
+// reconcileIdentity creates the tunnel identity Secret, or regenerates it when a rotation is requested
+// It returns the fingerprint of the current identity
+func (r *PortalExposeReconciler) reconcileIdentity(ctx context.Context, portalExpose *portalv1alpha1.PortalExpose) (string, error) {
+	logger := log.FromContext(ctx)
+
+	existing := &corev1.Secret{}
+	key := types.NamespacedName{Name: tunnel.IdentitySecretName(portalExpose), Namespace: portalExpose.Namespace}
+	err := r.getTunnelSecret(ctx, key, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	found := err == nil
+	if found && !metav1.IsControlledBy(existing, portalExpose) {
+		return "", fmt.Errorf("secret %s exists and is not owned by this PortalExpose", key)
+	}
+	if found && !tunnel.NeedsIdentityRotation(portalExpose, existing) {
+		return tunnel.IdentityFingerprint(existing)
+	}
+
+	desired, err := tunnel.BuildIdentitySecret(portalExpose)
+	if err != nil {
+		return "", err
+	}
+	fingerprint, err := tunnel.IdentityFingerprint(desired)
+	if err != nil {
+		return "", err
+	}
+	if err := controllerutil.SetControllerReference(portalExpose, desired, r.Scheme); err != nil {
+		return "", err
+	}
+
+	if !found {
+		logger.Info("Creating tunnel identity Secret", "name", desired.Name, "fingerprint", fingerprint)
+		return fingerprint, r.Create(ctx, desired)
+	}
+
+	logger.Info("Rotating tunnel identity", "name", existing.Name, "fingerprint", fingerprint)
+	existing.Data = desired.Data
+	existing.Annotations = desired.Annotations
+	if err := r.Update(ctx, existing); err != nil {
+		return "", err
+	}
+	r.Recorder.Event(portalExpose, corev1.EventTypeNormal, util.EventReasonIdentityRotated,
+		fmt.Sprintf("Tunnel identity rotated, new fingerprint %s", fingerprint))
+	return fingerprint, nil
+}
+
+// getTunnelSecret reads a Secret mounted by the tunnel pods
+// The cache only holds Secrets labeled with tunnel.SecretLabel, so a Secret it misses, such as relay credentials
+// created by a user or the identity of an earlier controller version, is read from the API server and labeled,
+// which brings its later changes into the cache and the Secret watch
+func (r *PortalExposeReconciler) getTunnelSecret(ctx context.Context, key types.NamespacedName, secret *corev1.Secret) error {
+	if err := r.Get(ctx, key, secret); !errors.IsNotFound(err) {
+		return err
+	}
+	if err := r.apiReader().Get(ctx, key, secret); err != nil {
+		return err
+	}
+	if secret.Labels[tunnel.SecretLabel] == "true" {
+		return nil // Labeled, but not in the cache yet
+	}
+	log.FromContext(ctx).Info("Labeling Secret mounted by tunnel pods", "secret", key.Name)
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+	secret.Labels[tunnel.SecretLabel] = "true"
+	return r.Update(ctx, secret)
+}
+
+// apiReader returns the reader for objects the cache does not hold
+func (r *PortalExposeReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+// deleteTunnelDependents deletes the HorizontalPodAutoscaler, PodDisruptionBudget and NetworkPolicy
+// created next to the tunnel Deployment, if any
+func (r *PortalExposeReconciler) deleteTunnelDependents(ctx context.Context, portalExpose *portalv1alpha1.PortalExpose) error {
+	dependents := []client.Object{
+		&autoscalingv2.HorizontalPodAutoscaler{},
+		&policyv1.PodDisruptionBudget{},
+		&networkingv1.NetworkPolicy{},
+	}
+	for _, obj := range dependents {
+		obj.SetName(portalExpose.Name + "-tunnel")
+		obj.SetNamespace(portalExpose.Namespace)
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // updateStatusFromDeployment computes and updates the status based on Deployment state
@@ -211,51 +655,73 @@ func (r *PortalExposeReconciler) updateStatusFromDeployment(
 	portalExpose *portalv1alpha1.PortalExpose,
 	existingDeployment *appsv1.Deployment,
 	tunnelClass *portalv1alpha1.TunnelClass,
+	relays []tunnel.RelayEndpoint,
+	endpoints []tunnel.AppEndpoint,
 ) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
 	readyReplicas := existingDeployment.Status.ReadyReplicas
-	desiredReplicas := tunnelClass.Spec.Replicas
+	// The HPA may have scaled the Deployment away from the TunnelClass replicas
+	desiredReplicas := ptr.Deref(existingDeployment.Spec.Replicas, tunnelClass.Spec.Replicas)
 
 	portalExpose.Status.TunnelPods.Ready = readyReplicas
 	portalExpose.Status.TunnelPods.Total = desiredReplicas
 
-	// Compute relay connection status (simplified for MVP)
-	podsReady := (readyReplicas > 0)
-	relayStatuses := tunnel.ComputeRelayStatuses(portalExpose.Spec.Relay.Targets, podsReady)
+	// Compute relay connection status from the session state reported by each tunnel pod
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods,
+		client.InNamespace(existingDeployment.Namespace),
+		client.MatchingLabels(existingDeployment.Spec.Selector.MatchLabels)); err != nil {
+		logger.Error(err, "Failed to list tunnel pods")
+		return ctrl.Result{}, err
+	}
+	reports := tunnel.CollectRelayReports(ctx, r.statusClient(), pods.Items)
+	relayStatuses := tunnel.ComputeRelayStatuses(relays, reports)
 	portalExpose.Status.Relay.Connected = relayStatuses
 
 	// Compute phase
 	connectedRelays := countConnectedRelays(relayStatuses)
 	portalExpose.Status.Phase = tunnel.ComputePhase(readyReplicas, desiredReplicas, connectedRelays, len(relayStatuses))
 
-	// Ensure public URL is set
-	if portalExpose.Status.PublicURL == "" {
-		primaryRelayURL := portalExpose.Spec.Relay.Targets[0].URL
-		portalExpose.Status.PublicURL = tunnel.ConstructPublicURL(portalExpose.Spec.App.Name, primaryRelayURL)
-	}
+	// Keep public URLs in sync with the primary relay, which may move with its ClusterRelay
+	portalExpose.Status.PublicURL = relays[0].PublicURL(portalExpose.Spec.App.Name)
+	portalExpose.Status.Endpoints = tunnel.ComputeEndpointStatuses(endpoints, relays[0])
 
 	// Update conditions
 	r.updateConditions(portalExpose, existingDeployment, readyReplicas, desiredReplicas, connectedRelays, len(relayStatuses))
 
 	// Update status
-	if err := r.Status().Update(ctx, portalExpose); err != nil {
+	if err := r.patchStatus(ctx, portalExpose); err != nil {
 		logger.Error(err, "Failed to update status")
 		return ctrl.Result{}, err
 	}
 
-	// Emit events for phase changes
-	r.emitPhaseEvents(portalExpose, readyReplicas, desiredReplicas, connectedRelays, len(relayStatuses))
-
 	logger.Info("Reconciliation complete", "phase", portalExpose.Status.Phase)
-	return ctrl.Result{}, nil
+	// Relay sessions change without any Kubernetes event, so poll them periodically
+	return ctrl.Result{RequeueAfter: relayStatusResyncInterval}, nil
+}
+
+// statusClient returns the configured tunnel status client or the HTTP default
+func (r *PortalExposeReconciler) statusClient() tunnel.StatusClient {
+	if r.StatusClient != nil {
+		return r.StatusClient
+	}
+	return tunnel.NewHTTPStatusClient()
+}
+
+// sizes returns the configured size tier table
+func (r *PortalExposeReconciler) sizes() tunnel.SizeTable {
+	if r.Sizes != nil {
+		return r.Sizes
+	}
+	return tunnel.DefaultSizes()
 }
 
 // countConnectedRelays counts the number of connected relays
 func countConnectedRelays(relayStatuses []portalv1alpha1.RelayConnectionStatus) int {
 	connectedRelays := 0
 	for _, rs := range relayStatuses {
-		if rs.Status == "Connected" {
+		if rs.Status == util.RelayStatusConnected {
 			connectedRelays++
 		}
 	}
@@ -273,60 +739,39 @@ func (r *PortalExposeReconciler) updateConditions(
 	allRelaysConnected := (connectedRelays == totalRelays && totalRelays > 0)
 
 	if allPodsReady {
-		util.SetCondition(&portalExpose.Status.Conditions, util.ConditionTunnelDeploymentReady, metav1.ConditionTrue,
+		util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionTunnelDeploymentReady, metav1.ConditionTrue,
 			"AllPodsReady", fmt.Sprintf("%d/%d tunnel pods ready", readyReplicas, desiredReplicas))
 	} else {
-		util.SetCondition(&portalExpose.Status.Conditions, util.ConditionTunnelDeploymentReady, metav1.ConditionFalse,
+		util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionTunnelDeploymentReady, metav1.ConditionFalse,
 			"PodsNotReady", fmt.Sprintf("Only %d/%d tunnel pods ready", readyReplicas, desiredReplicas))
 	}
 
 	if allRelaysConnected {
-		util.SetCondition(&portalExpose.Status.Conditions, util.ConditionRelayConnected, metav1.ConditionTrue,
+		util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionRelayConnected, metav1.ConditionTrue,
 			"AllRelaysConnected", fmt.Sprintf("Connected to %d/%d relays", connectedRelays, totalRelays))
 	} else {
-		util.SetCondition(&portalExpose.Status.Conditions, util.ConditionRelayConnected, metav1.ConditionFalse,
+		util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionRelayConnected, metav1.ConditionFalse,
 			"PartialRelayConnection", fmt.Sprintf("Only %d/%d relays connected", connectedRelays, totalRelays))
 	}
 
 	if portalExpose.Status.Phase == util.PhaseReady || portalExpose.Status.Phase == util.PhaseDegraded {
-		util.SetCondition(&portalExpose.Status.Conditions, util.ConditionAvailable, metav1.ConditionTrue,
+		util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionAvailable, metav1.ConditionTrue,
 			"PortalExposeAvailable", "PortalExpose is available")
 	} else {
-		util.SetCondition(&portalExpose.Status.Conditions, util.ConditionAvailable, metav1.ConditionFalse,
+		util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionAvailable, metav1.ConditionFalse,
 			"PortalExposeNotAvailable", fmt.Sprintf("PortalExpose is %s", portalExpose.Status.Phase))
 	}
 
 	updating := (existingDeployment.Status.UpdatedReplicas < desiredReplicas)
 	if updating {
-		util.SetCondition(&portalExpose.Status.Conditions, util.ConditionProgressing, metav1.ConditionTrue,
+		util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionProgressing, metav1.ConditionTrue,
 			"RollingUpdate", "Deployment rolling update in progress")
 	} else {
-		util.SetCondition(&portalExpose.Status.Conditions, util.ConditionProgressing, metav1.ConditionFalse,
+		util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionProgressing, metav1.ConditionFalse,
 			"DeploymentStable", "No rolling update in progress")
 	}
 }
 
-// emitPhaseEvents emits events based on the current phase
-func (r *PortalExposeReconciler) emitPhaseEvents(
-	portalExpose *portalv1alpha1.PortalExpose,
-	readyReplicas, desiredReplicas int32,
-	connectedRelays, totalRelays int,
-) {
-	// Note: In production, we'd track previous phase to only emit on changes
-	switch portalExpose.Status.Phase {
-	case util.PhaseReady:
-		r.Recorder.Event(portalExpose, corev1.EventTypeNormal, "Ready",
-			"All tunnel pods and relays are healthy")
-	case util.PhaseDegraded:
-		r.Recorder.Event(portalExpose, corev1.EventTypeWarning, "Degraded",
-			fmt.Sprintf("Partial failure: %d/%d pods ready, %d/%d relays connected",
-				readyReplicas, desiredReplicas, connectedRelays, totalRelays))
-	case util.PhaseFailed:
-		r.Recorder.Event(portalExpose, corev1.EventTypeWarning, "Failed",
-			"All tunnel pods or relays unavailable")
-	}
-}
-
 // handleDeletion handles cleanup when PortalExpose is being deleted
 func (r *PortalExposeReconciler) handleDeletion(ctx context.Context, portalExpose *portalv1alpha1.PortalExpose) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -337,6 +782,23 @@ func (r *PortalExposeReconciler) handleDeletion(ctx context.Context, portalExpos
 		return ctrl.Result{}, nil
 	}
 
+	// Delete the objects created next to the tunnel Deployment
+	if err := r.deleteTunnelDependents(ctx, portalExpose); err != nil {
+		logger.Error(err, "Failed to delete tunnel dependents")
+		return ctrl.Result{}, err
+	}
+	identity := &corev1.Secret{}
+	identityKey := types.NamespacedName{Name: tunnel.IdentitySecretName(portalExpose), Namespace: portalExpose.Namespace}
+	if err := r.apiReader().Get(ctx, identityKey, identity); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, "Failed to get tunnel identity Secret")
+		return ctrl.Result{}, err
+	} else if err == nil && metav1.IsControlledBy(identity, portalExpose) {
+		if err := r.Delete(ctx, identity); client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "Failed to delete tunnel identity Secret")
+			return ctrl.Result{}, err
+		}
+	}
+
 	// Delete tunnel Deployment
 	deployment := &appsv1.Deployment{}
 	deploymentKey := types.NamespacedName{
@@ -368,56 +830,216 @@ func (r *PortalExposeReconciler) handleDeletion(ctx context.Context, portalExpos
 		return ctrl.Result{}, err
 	}
 
-	r.Recorder.Event(portalExpose, corev1.EventTypeNormal, "Deleted",
+	r.Recorder.Event(portalExpose, corev1.EventTypeNormal, util.EventReasonDeleted,
 		"PortalExpose deleted, tunnel pods cleaned up")
 
 	logger.Info("PortalExpose deletion complete")
 	return ctrl.Result{}, nil
 }
 
-// resolveTunnelClass finds the TunnelClass to use (specified or default)
+// handleNameConflict stops the tunnel of a PortalExpose that lost its app name to an older one
+func (r *PortalExposeReconciler) handleNameConflict(
+	ctx context.Context,
+	portalExpose *portalv1alpha1.PortalExpose,
+	conflict *nameConflict,
+) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	owner := client.ObjectKeyFromObject(conflict.Owner).String()
+	message := fmt.Sprintf("App name '%s' on relay domain '%s' is already claimed by PortalExpose '%s'",
+		conflict.Subdomain, conflict.Domain, owner)
+	logger.Info("App name conflict", "subdomain", conflict.Subdomain, "domain", conflict.Domain, "owner", owner)
+
+	// Stop the tunnel so that the two exposures do not fight over the name
+	if err := r.deleteTunnelDependents(ctx, portalExpose); err != nil {
+		logger.Error(err, "Failed to delete tunnel dependents")
+		return ctrl.Result{}, err
+	}
+	deployment := &appsv1.Deployment{}
+	deploymentKey := types.NamespacedName{
+		Name:      portalExpose.Name + "-tunnel",
+		Namespace: portalExpose.Namespace,
+	}
+	if err := r.Get(ctx, deploymentKey, deployment); err == nil {
+		logger.Info("Deleting tunnel Deployment of conflicting PortalExpose", "name", deployment.Name)
+		if err := r.Delete(ctx, deployment); client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "Failed to delete Deployment")
+			return ctrl.Result{}, err
+		}
+	} else if !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to get Deployment")
+		return ctrl.Result{}, err
+	}
+
+	portalExpose.Status.Phase = util.PhaseFailed
+	portalExpose.Status.PublicURL = ""
+	portalExpose.Status.Endpoints = nil
+	util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionNameConflict, metav1.ConditionTrue,
+		"NameConflict", message)
+	util.SetCondition(&portalExpose.Status.Conditions, portalExpose.Generation, util.ConditionAvailable, metav1.ConditionFalse,
+		"NameConflict", "PortalExpose failed due to an app name conflict")
+
+	if err := r.patchStatus(ctx, portalExpose); err != nil {
+		logger.Error(err, "Failed to update status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil // Re-evaluated when the owning PortalExpose changes or is deleted
+}
+
+// resolveTunnelClass finds the TunnelClass to use (specified, namespace default or cluster default)
 func (r *PortalExposeReconciler) resolveTunnelClass(ctx context.Context, portalExpose *portalv1alpha1.PortalExpose) (*portalv1alpha1.TunnelClass, error) {
-	return tunnelclass.GetTunnelClass(ctx, r.Client, portalExpose.Spec.TunnelClassName)
+	return tunnelclass.GetTunnelClass(ctx, r.Client, portalExpose.Namespace, portalExpose.Spec.TunnelClassName)
 }
 
-// deploymentSpecEqual checks if two Deployment specs are equal
-// Simplified comparison for MVP
-func deploymentSpecEqual(existing, desired *appsv1.Deployment) bool {
-	// Compare key fields that trigger updates
-	if *existing.Spec.Replicas != *desired.Spec.Replicas {
-		return false
+// upgradeManagedFields merges the managed fields of the legacy Update manager into the apply manager
+// It runs once, before the first apply, so later Update calls of other clients sharing the default
+// manager name keep their fields
+// The patch carries the resourceVersion it was computed from, so it fails with a conflict on a stale Deployment
+func (r *PortalExposeReconciler) upgradeManagedFields(ctx context.Context, deployment *appsv1.Deployment) error {
+	if appliesField(deployment) {
+		return nil
 	}
-
-	// Compare container image and resources
-	if len(existing.Spec.Template.Spec.Containers) != len(desired.Spec.Template.Spec.Containers) {
-		return false
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(deployment, sets.New(legacyFieldManager), fieldManager)
+	if err != nil || patch == nil {
+		return err
 	}
+	log.FromContext(ctx).Info("Migrating Deployment fields to server-side apply", "name", deployment.Name,
+		"fromManager", legacyFieldManager)
+	return r.Patch(ctx, deployment, client.RawPatch(types.JSONPatchType, patch))
+}
 
-	for i := range existing.Spec.Template.Spec.Containers {
-		existingContainer := existing.Spec.Template.Spec.Containers[i]
-		desiredContainer := desired.Spec.Template.Spec.Containers[i]
-
-		if existingContainer.Image != desiredContainer.Image {
-			return false
+// appliesField reports whether the controller's apply configuration owns the field at path
+func appliesField(obj metav1.Object, path ...string) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager != fieldManager || entry.Operation != metav1.ManagedFieldsOperationApply || entry.FieldsV1 == nil {
+			continue
 		}
-
-		// Compare resources (simplified)
-		if !existingContainer.Resources.Requests.Cpu().Equal(*desiredContainer.Resources.Requests.Cpu()) {
+		var fields map[string]any
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
 			return false
 		}
-		if !existingContainer.Resources.Requests.Memory().Equal(*desiredContainer.Resources.Requests.Memory()) {
-			return false
+		for _, name := range path {
+			next, ok := fields["f:"+name].(map[string]any)
+			if !ok {
+				return false
+			}
+			fields = next
 		}
+		return true
+	}
+	return false
+}
+
+// portalExposesForClusterRelay maps a ClusterRelay to the PortalExposes referencing it
+func (r *PortalExposeReconciler) portalExposesForClusterRelay(ctx context.Context, obj client.Object) []reconcile.Request {
+	portalExposes := &portalv1alpha1.PortalExposeList{}
+	if err := r.List(ctx, portalExposes, client.MatchingFields{clusterRelayRefIndex: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list PortalExposes for ClusterRelay", "clusterRelay", obj.GetName())
+		return nil
 	}
+	return requestsFor(portalExposes)
+}
+
+// portalExposesForService maps a Service to the PortalExposes in its namespace exposing it from any endpoint
+func (r *PortalExposeReconciler) portalExposesForService(ctx context.Context, obj client.Object) []reconcile.Request {
+	portalExposes := &portalv1alpha1.PortalExposeList{}
+	if err := r.List(ctx, portalExposes, client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{serviceNameIndex: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list PortalExposes for Service", "service", obj.GetName())
+		return nil
+	}
+	return requestsFor(portalExposes)
+}
 
-	return true
+// portalExposesForSecret maps a Secret to the PortalExposes using it as relay credentials
+func (r *PortalExposeReconciler) portalExposesForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	portalExposes := &portalv1alpha1.PortalExposeList{}
+	if err := r.List(ctx, portalExposes, client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{credentialsSecretIndex: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list PortalExposes for Secret", "secret", obj.GetName())
+		return nil
+	}
+	return requestsFor(portalExposes)
+}
+
+// portalExposesForTunnelClass maps a TunnelClass to the PortalExposes naming it, and to the PortalExposes
+// without a class while it carries the default-class annotation or their namespace picks it as its default
+// Updates are mapped for both the old and the new object, so removing the annotation is seen too
+func (r *PortalExposeReconciler) portalExposesForTunnelClass(ctx context.Context, obj client.Object) []reconcile.Request {
+	portalExposes := &portalv1alpha1.PortalExposeList{}
+	if err := r.List(ctx, portalExposes, client.MatchingFields{tunnelClassNameIndex: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list PortalExposes for TunnelClass", "tunnelClass", obj.GetName())
+		return nil
+	}
+	requests := requestsFor(portalExposes)
+
+	unclassified := &portalv1alpha1.PortalExposeList{}
+	if err := r.List(ctx, unclassified, client.MatchingFields{tunnelClassNameIndex: ""}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list PortalExposes for TunnelClass", "tunnelClass", obj.GetName())
+		return nil
+	}
+	isDefault := tunnelclass.IsDefault(obj.(*portalv1alpha1.TunnelClass))
+	for i := range unclassified.Items {
+		pe := &unclassified.Items[i]
+		if !isDefault {
+			namespace := &corev1.Namespace{}
+			if err := r.Get(ctx, client.ObjectKey{Name: pe.Namespace}, namespace); err != nil ||
+				namespace.Annotations[tunnelclass.NamespaceDefaultClassAnnotation] != obj.GetName() {
+				continue
+			}
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pe)})
+	}
+	return requests
+}
+
+// portalExposesForNamespace maps a Namespace to its PortalExposes, whose TunnelClass
+// can change with its default-class annotation or its labels matched by allowedNamespaces
+func (r *PortalExposeReconciler) portalExposesForNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	portalExposes := &portalv1alpha1.PortalExposeList{}
+	if err := r.List(ctx, portalExposes, client.InNamespace(obj.GetName())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list PortalExposes for Namespace", "namespace", obj.GetName())
+		return nil
+	}
+	return requestsFor(portalExposes)
+}
+
+// requestsFor returns a reconcile request for every PortalExpose of the list
+func requestsFor(portalExposes *portalv1alpha1.PortalExposeList) []reconcile.Request {
+	requests := make([]reconcile.Request, 0, len(portalExposes.Items))
+	for i := range portalExposes.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&portalExposes.Items[i]),
+		})
+	}
+	return requests
 }
 
 // SetupWithManager sets up the controller with the Manager.
+// Requires the field indexes registered by SetupIndexes.
 func (r *PortalExposeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&portalv1alpha1.PortalExpose{}).
 		Owns(&appsv1.Deployment{}). // Watch Deployments owned by PortalExpose
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		Owns(&corev1.Secret{}). // Regenerate a deleted tunnel identity; Secrets are cached by label, see CacheByObject
+		Watches(&portalv1alpha1.ClusterRelay{},
+			handler.EnqueueRequestsFromMapFunc(r.portalExposesForClusterRelay)). // Roll out relay changes
+		Watches(&portalv1alpha1.PortalExpose{},
+			handler.EnqueueRequestsFromMapFunc(r.portalExposesWithSharedSubdomain)). // Re-evaluate name conflicts
+		Watches(&corev1.Service{},
+			handler.EnqueueRequestsFromMapFunc(r.portalExposesForService)). // Follow Service creation and port changes
+		Watches(&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.portalExposesForSecret)). // Roll out rotated relay credentials
+		Watches(&portalv1alpha1.TunnelClass{},
+			handler.EnqueueRequestsFromMapFunc(r.portalExposesForTunnelClass),
+			// Spec and default-class annotation changes; the class status is written by its own controller
+			builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
+		Watches(&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.portalExposesForNamespace),
+			// Default-class annotation and allowedNamespaces label changes
+			builder.WithPredicates(predicate.Or(predicate.LabelChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
 		Named("portalexpose").
 		Complete(r)
 }
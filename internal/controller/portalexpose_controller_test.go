@@ -26,6 +26,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
 )
@@ -56,7 +57,7 @@ var _ = Describe("PortalExpose Controller", func() {
 							Name: "test-app",
 							Service: portalv1alpha1.ServiceRef{
 								Name: "test-service",
-								Port: 8080,
+								Port: intstr.FromInt32(8080),
 							},
 						},
 						Relay: portalv1alpha1.RelaySpec{
@@ -84,9 +85,12 @@ var _ = Describe("PortalExpose Controller", func() {
 		})
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
+			Eventually(func() error {
+				return mgrClient.Get(ctx, typeNamespacedName, &portalv1alpha1.PortalExpose{})
+			}).Should(Succeed())
 			controllerReconciler := &PortalExposeReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client: mgrClient,
+				Scheme: mgrClient.Scheme(),
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -0,0 +1,129 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/clusterrelay"
+	"github.com/gosuda/portal-expose/internal/tunnel"
+)
+
+// nameConflict describes an older PortalExpose claiming the same subdomain on a shared relay domain
+type nameConflict struct {
+	// Owner is the oldest PortalExpose holding the name
+	Owner *portalv1alpha1.PortalExpose
+
+	// Subdomain is the name both exposures publish
+	Subdomain string
+
+	// Domain is the relay domain both exposures publish on
+	Domain string
+}
+
+// findNameConflict returns the oldest other PortalExpose publishing any of this PortalExpose's
+// subdomains on any of the given relay domains, or nil when this PortalExpose owns all its names
+func (r *PortalExposeReconciler) findNameConflict(
+	ctx context.Context,
+	portalExpose *portalv1alpha1.PortalExpose,
+	relays []tunnel.RelayEndpoint,
+) (*nameConflict, error) {
+	domains := make(map[string]bool, len(relays))
+	for _, relay := range relays {
+		domains[relay.PublicDomain] = true
+	}
+
+	var conflict *nameConflict
+	for _, subdomain := range tunnel.Subdomains(portalExpose) {
+		candidates := &portalv1alpha1.PortalExposeList{}
+		if err := r.List(ctx, candidates, client.MatchingFields{subdomainIndex: subdomain}); err != nil {
+			return nil, err
+		}
+
+		for i := range candidates.Items {
+			other := &candidates.Items[i]
+			if other.UID == portalExpose.UID || !other.DeletionTimestamp.IsZero() || !claimedBefore(other, portalExpose) {
+				continue
+			}
+			if conflict != nil && !claimedBefore(other, conflict.Owner) {
+				continue
+			}
+
+			otherRelays, err := clusterrelay.ResolveTargets(ctx, r.Client, other.Spec.Relay.Targets)
+			if err != nil {
+				// An exposure whose relays cannot be resolved publishes nothing
+				continue
+			}
+			for _, relay := range otherRelays {
+				if domains[relay.PublicDomain] {
+					conflict = &nameConflict{Owner: other, Subdomain: subdomain, Domain: relay.PublicDomain}
+					break
+				}
+			}
+		}
+	}
+
+	return conflict, nil
+}
+
+// claimedBefore reports whether a was created before b, breaking ties by namespace/name
+func claimedBefore(a, b *portalv1alpha1.PortalExpose) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+// portalExposesWithSharedSubdomain maps a PortalExpose to the other PortalExposes claiming any of its subdomains,
+// so that losers of a name conflict are re-evaluated when the owner changes or goes away
+func (r *PortalExposeReconciler) portalExposesWithSharedSubdomain(ctx context.Context, obj client.Object) []reconcile.Request {
+	portalExpose, ok := obj.(*portalv1alpha1.PortalExpose)
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[types.UID]bool)
+	var requests []reconcile.Request
+	for _, subdomain := range tunnel.Subdomains(portalExpose) {
+		candidates := &portalv1alpha1.PortalExposeList{}
+		if err := r.List(ctx, candidates, client.MatchingFields{subdomainIndex: subdomain}); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to list PortalExposes with the same subdomain", "subdomain", subdomain)
+			return nil
+		}
+
+		for i := range candidates.Items {
+			candidate := &candidates.Items[i]
+			if candidate.UID == portalExpose.UID || seen[candidate.UID] {
+				continue
+			}
+			seen[candidate.UID] = true
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(candidate),
+			})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/autoexpose"
+	"github.com/gosuda/portal-expose/internal/util"
+)
+
+// ServiceExposeReconciler creates PortalExposes for Services annotated with portal.gosuda.org/expose
+type ServiceExposeReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+// +kubebuilder:rbac:groups=portal.gosuda.org,resources=portalexposes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile keeps the PortalExpose owned by an annotated Service in sync with its annotations
+func (r *ServiceExposeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	service := &corev1.Service{}
+	if err := r.Get(ctx, req.NamespacedName, service); err != nil {
+		if errors.IsNotFound(err) {
+			// The owned PortalExpose is garbage collected with the Service
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get Service")
+		return ctrl.Result{}, err
+	}
+
+	existing := &portalv1alpha1.PortalExpose{}
+	err := r.Get(ctx, req.NamespacedName, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to get PortalExpose")
+		return ctrl.Result{}, err
+	}
+	found := err == nil
+
+	// Annotation removed (or never set): drop the PortalExpose we created, leave any other alone
+	if !autoexpose.IsExposed(service) || !service.DeletionTimestamp.IsZero() {
+		if found && metav1.IsControlledBy(existing, service) && existing.DeletionTimestamp.IsZero() {
+			logger.Info("Deleting PortalExpose of Service no longer annotated for exposure", "name", existing.Name)
+			if err := r.Delete(ctx, existing); client.IgnoreNotFound(err) != nil {
+				logger.Error(err, "Failed to delete PortalExpose")
+				return ctrl.Result{}, err
+			}
+			r.Recorder.Event(service, corev1.EventTypeNormal, util.EventReasonUnexposed,
+				fmt.Sprintf("Deleted PortalExpose '%s'", existing.Name))
+		}
+		return ctrl.Result{}, nil
+	}
+
+	desired, err := autoexpose.BuildPortalExpose(service)
+	if err != nil {
+		logger.Info("Invalid exposure annotations", "error", err.Error())
+		r.Recorder.Event(service, corev1.EventTypeWarning, util.EventReasonInvalidAnnotations, err.Error())
+		return ctrl.Result{}, nil // Wait for the annotations to change
+	}
+
+	if !found {
+		if err := controllerutil.SetControllerReference(service, desired, r.Scheme); err != nil {
+			logger.Error(err, "Failed to set controller reference")
+			return ctrl.Result{}, err
+		}
+		logger.Info("Creating PortalExpose for annotated Service", "name", desired.Name)
+		if err := r.Create(ctx, desired); err != nil {
+			logger.Error(err, "Failed to create PortalExpose")
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Event(service, corev1.EventTypeNormal, util.EventReasonExposed,
+			fmt.Sprintf("Created PortalExpose '%s'", desired.Name))
+		return ctrl.Result{}, nil
+	}
+
+	// Never take over a PortalExpose someone wrote by hand
+	if !metav1.IsControlledBy(existing, service) {
+		r.Recorder.Event(service, corev1.EventTypeWarning, util.EventReasonPortalExposeExists,
+			fmt.Sprintf("PortalExpose '%s' already exists and is not managed by this Service", existing.Name))
+		return ctrl.Result{}, nil
+	}
+
+	labelsInSync := true
+	for key, value := range desired.Labels {
+		if existing.Labels[key] != value {
+			labelsInSync = false
+		}
+	}
+	if labelsInSync && equality.Semantic.DeepEqual(existing.Spec, desired.Spec) {
+		return ctrl.Result{}, nil
+	}
+
+	logger.Info("Updating PortalExpose from Service annotations", "name", existing.Name)
+	existing.Spec = desired.Spec
+	if existing.Labels == nil {
+		existing.Labels = map[string]string{}
+	}
+	for key, value := range desired.Labels {
+		existing.Labels[key] = value
+	}
+	if err := r.Update(ctx, existing); err != nil {
+		logger.Error(err, "Failed to update PortalExpose")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// serviceSpecChanged passes Service updates that change the spec
+// Services carry no metadata.generation, so GenerationChangedPredicate would drop them
+var serviceSpecChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldService, ok := e.ObjectOld.(*corev1.Service)
+		if !ok {
+			return false
+		}
+		newService, ok := e.ObjectNew.(*corev1.Service)
+		if !ok {
+			return false
+		}
+		return !equality.Semantic.DeepEqual(oldService.Spec, newService.Spec)
+	},
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ServiceExposeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		// The translation reads the annotations and falls back to the first Service port, so warnings
+		// are not repeated on resyncs or load balancer status updates
+		For(&corev1.Service{}, builder.WithPredicates(predicate.Or(predicate.AnnotationChangedPredicate{}, serviceSpecChanged))).
+		// Restore spec and label edits to generated PortalExposes, ignoring their status updates
+		Owns(&portalv1alpha1.PortalExpose{},
+			builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))).
+		Named("service-expose").
+		Complete(r)
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/util"
+)
+
+// patchStatusWithRetry writes a computed status through a merge patch locked to the resourceVersion
+// Every attempt re-reads the object and computes the status onto it with setStatus, so a concurrent
+// writer costs a retry instead of a failed reconcile and a hot requeue
+func patchStatusWithRetry[T client.Object](
+	ctx context.Context,
+	c client.Client,
+	obj T,
+	setStatus func(latest T) error,
+) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := obj.DeepCopyObject().(T)
+		if err := c.Get(ctx, client.ObjectKeyFromObject(obj), latest); err != nil {
+			return err
+		}
+		original := latest.DeepCopyObject().(T)
+		if err := setStatus(latest); err != nil {
+			return err
+		}
+		if equality.Semantic.DeepEqual(original, latest) {
+			return nil
+		}
+		return c.Status().Patch(ctx, latest, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+	})
+}
+
+// patchStatus writes the status computed on portalExpose, recording the generation it observed
+// Events are recorded for the phase and condition changes the patch made to the stored status
+func (r *PortalExposeReconciler) patchStatus(ctx context.Context, portalExpose *portalv1alpha1.PortalExpose) error {
+	portalExpose.Status.ObservedGeneration = portalExpose.Generation
+	var previous portalv1alpha1.PortalExposeStatus
+	err := patchStatusWithRetry(ctx, r.Client, portalExpose, func(latest *portalv1alpha1.PortalExpose) error {
+		latest.Status.DeepCopyInto(&previous)
+		portalExpose.Status.DeepCopyInto(&latest.Status)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.emitTransitionEvents(portalExpose, &previous)
+	return nil
+}
+
+// emitTransitionEvents records an event for the phase and for every condition whose status changed
+func (r *PortalExposeReconciler) emitTransitionEvents(
+	portalExpose *portalv1alpha1.PortalExpose,
+	previous *portalv1alpha1.PortalExposeStatus,
+) {
+	var transitions []util.Transition
+	if transition, ok := util.PhaseTransition(previous.Phase, portalExpose.Status.Phase, phaseDetail(&portalExpose.Status)); ok {
+		transitions = append(transitions, transition)
+	}
+	transitions = append(transitions, util.ConditionTransitions(previous.Conditions, portalExpose.Status.Conditions)...)
+
+	for _, transition := range transitions {
+		eventType := corev1.EventTypeNormal
+		if transition.Warning {
+			eventType = corev1.EventTypeWarning
+		}
+		r.Recorder.Event(portalExpose, eventType, transition.Reason, transition.Message)
+	}
+}
+
+// phaseDetail summarizes the tunnel state behind a Ready or Degraded phase
+// Other phases are explained by the condition events recorded with them
+func phaseDetail(status *portalv1alpha1.PortalExposeStatus) string {
+	if status.Phase != util.PhaseReady && status.Phase != util.PhaseDegraded {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d tunnel pods ready, %d/%d relays connected",
+		status.TunnelPods.Ready, status.TunnelPods.Total,
+		countConnectedRelays(status.Relay.Connected), len(status.Relay.Connected))
+}
+
+// patchStatus writes the status computed on tunnelClass, recording the generation it observed
+func (r *TunnelClassReconciler) patchStatus(ctx context.Context, tunnelClass *portalv1alpha1.TunnelClass) error {
+	tunnelClass.Status.ObservedGeneration = tunnelClass.Generation
+	return patchStatusWithRetry(ctx, r.Client, tunnelClass, func(latest *portalv1alpha1.TunnelClass) error {
+		tunnelClass.Status.DeepCopyInto(&latest.Status)
+		return nil
+	})
+}
@@ -18,26 +18,51 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/tunnel"
+	"github.com/gosuda/portal-expose/internal/tunnelclass"
+	"github.com/gosuda/portal-expose/internal/util"
 )
 
 // TunnelClassReconciler reconciles a TunnelClass object
 type TunnelClassReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// Recorder audits security overrides; optional
+	Recorder record.EventRecorder
+
+	// Sizes maps TunnelClass size tiers to container resources
+	// Defaults to tunnel.DefaultSizes() when nil
+	Sizes tunnel.SizeTable
 }
 
 // +kubebuilder:rbac:groups=portal.gosuda.org,resources=tunnelclasses,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=portal.gosuda.org,resources=tunnelclasses/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=portal.gosuda.org,resources=tunnelclasses/finalizers,verbs=update
+// +kubebuilder:rbac:groups=portal.gosuda.org,resources=portalexposes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
-// Reconcile ensures TunnelClass consistency, particularly around default class handling
+// Reconcile reports TunnelClass validity, usage and default class conflicts
 func (r *TunnelClassReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 
@@ -52,62 +77,254 @@ func (r *TunnelClassReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, nil
 	}
 
-	// Check if this TunnelClass is marked as default
-	isDefault := tunnelClass.Annotations != nil &&
-		tunnelClass.Annotations["portal.gosuda.org/is-default-class"] == "true"
+	// Several classes can be marked default when admissions race; only the selected one serves PortalExposes without a class
+	var selectedDefault string
+	if tunnelclass.IsDefault(tunnelClass) {
+		defaultClass, err := tunnelclass.GetDefaultTunnelClass(ctx, r.Client)
+		if err != nil {
+			log.Error(err, "unable to select the default TunnelClass")
+			return ctrl.Result{}, err
+		}
+		selectedDefault = defaultClass.Name
+	}
+
+	// Count the PortalExposes using this class
+	exposureCount, err := r.countExposures(ctx, tunnelClass, selectedDefault == tunnelClass.Name)
+	if err != nil {
+		log.Error(err, "unable to count PortalExposes using TunnelClass")
+		return ctrl.Result{}, err
+	}
+
+	// Keep a class in use until its last PortalExpose moves away or is deleted
+	if !tunnelClass.DeletionTimestamp.IsZero() {
+		if exposureCount > 0 {
+			log.Info("TunnelClass deletion blocked while in use", "name", tunnelClass.Name, "exposureCount", exposureCount)
+			tunnelClass.Status.ExposureCount = exposureCount
+			return ctrl.Result{}, r.patchStatus(ctx, tunnelClass) // Re-evaluated when a PortalExpose changes
+		}
+		if util.RemoveFinalizer(tunnelClass, util.TunnelClassFinalizerName) {
+			if err := r.Update(ctx, tunnelClass); err != nil {
+				log.Error(err, "failed to remove finalizer")
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
 
-	if isDefault {
-		// Ensure only one TunnelClass is default
-		if err := r.ensureOnlyOneDefault(ctx, tunnelClass); err != nil {
-			log.Error(err, "failed to ensure only one default TunnelClass")
+	if util.AddFinalizer(tunnelClass, util.TunnelClassFinalizerName) {
+		if err := r.Update(ctx, tunnelClass); err != nil {
+			log.Error(err, "failed to add finalizer")
 			return ctrl.Result{}, err
 		}
 	}
 
-	log.V(1).Info("TunnelClass reconciled", "name", tunnelClass.Name, "isDefault", isDefault)
+	// Report spec validation, unknown size tiers, audited security overrides, default conflicts and usage
+	if err := r.updateStatus(ctx, tunnelClass, exposureCount, selectedDefault); err != nil {
+		log.Error(err, "failed to update TunnelClass status")
+		return ctrl.Result{}, err
+	}
+
+	log.V(1).Info("TunnelClass reconciled", "name", tunnelClass.Name, "selectedDefault", selectedDefault, "exposureCount", exposureCount)
 	return ctrl.Result{}, nil
 }
 
-// ensureOnlyOneDefault removes the default annotation from other TunnelClasses
-func (r *TunnelClassReconciler) ensureOnlyOneDefault(ctx context.Context, newDefault *portalv1alpha1.TunnelClass) error {
-	log := logf.FromContext(ctx)
+// countExposures counts the PortalExposes naming the class, and those without a class in namespaces
+// picking it as their default or, elsewhere, while it is the selected default
+func (r *TunnelClassReconciler) countExposures(
+	ctx context.Context,
+	tunnelClass *portalv1alpha1.TunnelClass,
+	selectedDefault bool,
+) (int32, error) {
+	portalExposes := &portalv1alpha1.PortalExposeList{}
+	if err := r.List(ctx, portalExposes, client.MatchingFields{tunnelClassNameIndex: tunnelClass.Name}); err != nil {
+		return 0, err
+	}
+	count := int32(len(portalExposes.Items))
 
-	// List all TunnelClasses
-	tunnelClasses := &portalv1alpha1.TunnelClassList{}
-	if err := r.List(ctx, tunnelClasses); err != nil {
-		return err
+	unclassified := &portalv1alpha1.PortalExposeList{}
+	if err := r.List(ctx, unclassified, client.MatchingFields{tunnelClassNameIndex: ""}); err != nil {
+		return 0, err
+	}
+	namespaceDefaults := map[string]string{}
+	for _, pe := range unclassified.Items {
+		namespaceDefault, ok := namespaceDefaults[pe.Namespace]
+		if !ok {
+			namespace := &corev1.Namespace{}
+			if err := r.Get(ctx, client.ObjectKey{Name: pe.Namespace}, namespace); client.IgnoreNotFound(err) != nil {
+				return 0, err
+			}
+			namespaceDefault = namespace.Annotations[tunnelclass.NamespaceDefaultClassAnnotation]
+			namespaceDefaults[pe.Namespace] = namespaceDefault
+		}
+		if namespaceDefault == tunnelClass.Name || (namespaceDefault == "" && selectedDefault) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// updateStatus sets the exposure count and the Valid, InvalidSize, RestrictedPodSecurity and DefaultConflict conditions,
+// and writes the status if it changed
+func (r *TunnelClassReconciler) updateStatus(
+	ctx context.Context,
+	tunnelClass *portalv1alpha1.TunnelClass,
+	exposureCount int32,
+	selectedDefault string,
+) error {
+	sizes := r.Sizes
+	if sizes == nil {
+		sizes = tunnel.DefaultSizes()
+	}
+
+	before := tunnelClass.Status.DeepCopy()
+	tunnelClass.Status.ExposureCount = exposureCount
+	_, sizeErr := sizes.Resources(tunnelClass)
+
+	// Specs the webhook would reject can still be stored while it is unavailable
+	allErrs := tunnelclass.ValidateSpec(tunnelClass)
+	if sizeErr != nil {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "size"), tunnelClass.Spec.Size, sizeErr.Error()))
+	}
+	if len(allErrs) > 0 {
+		util.SetCondition(&tunnelClass.Status.Conditions, tunnelClass.Generation, util.ConditionValid, metav1.ConditionFalse,
+			"InvalidSpec", allErrs.ToAggregate().Error())
+	} else {
+		util.SetCondition(&tunnelClass.Status.Conditions, tunnelClass.Generation, util.ConditionValid, metav1.ConditionTrue,
+			"Valid", "Spec is valid")
 	}
 
-	// Remove default annotation from other TunnelClasses
-	for i := range tunnelClasses.Items {
-		tc := &tunnelClasses.Items[i]
+	switch {
+	case sizeErr != nil:
+		util.SetCondition(&tunnelClass.Status.Conditions, tunnelClass.Generation, util.ConditionInvalidSize, metav1.ConditionTrue,
+			"UnknownSize", sizeErr.Error())
+	case tunnelClass.Spec.Resources != nil:
+		util.SetCondition(&tunnelClass.Status.Conditions, tunnelClass.Generation, util.ConditionInvalidSize, metav1.ConditionFalse,
+			"ExplicitResources", "Tunnel containers use the resources set on the class")
+	default:
+		util.SetCondition(&tunnelClass.Status.Conditions, tunnelClass.Generation, util.ConditionInvalidSize, metav1.ConditionFalse,
+			"SizeResolved", fmt.Sprintf("Size '%s' is a known tier", tunnelClass.Spec.Size))
+	}
 
-		// Skip the new default
-		if tc.Name == newDefault.Name {
-			continue
+	override := tunnel.ActiveSecurityOverride(tunnelClass)
+	switch {
+	case override != nil:
+		status := metav1.ConditionTrue
+		if tunnel.ViolatesRestricted(override) {
+			status = metav1.ConditionFalse
 		}
+		util.SetCondition(&tunnelClass.Status.Conditions, tunnelClass.Generation, util.ConditionRestrictedPodSecurity, status,
+			"SecurityOverride", fmt.Sprintf("Relaxed %s: %s",
+				strings.Join(tunnel.RelaxedSettings(override), ", "),
+				tunnelClass.Annotations[tunnel.SecurityOverrideReasonAnnotation]))
+	case tunnelClass.Spec.SecurityOverride != nil:
+		util.SetCondition(&tunnelClass.Status.Conditions, tunnelClass.Generation, util.ConditionRestrictedPodSecurity, metav1.ConditionTrue,
+			"OverrideIgnored", fmt.Sprintf("securityOverride is ignored without the %s annotation",
+				tunnel.SecurityOverrideReasonAnnotation))
+	default:
+		util.SetCondition(&tunnelClass.Status.Conditions, tunnelClass.Generation, util.ConditionRestrictedPodSecurity, metav1.ConditionTrue,
+			"Restricted", "Tunnel pods meet the restricted Pod Security Standard")
+	}
 
-		// Check if this one is also marked as default
-		if tc.Annotations != nil && tc.Annotations["portal.gosuda.org/is-default-class"] == "true" {
-			log.Info("Removing default annotation from previous default TunnelClass",
-				"name", tc.Name, "newDefault", newDefault.Name)
+	// Losing defaults keep their annotation; an admin decides which one to remove
+	switch selectedDefault {
+	case "":
+		meta.RemoveStatusCondition(&tunnelClass.Status.Conditions, util.ConditionDefaultConflict)
+	case tunnelClass.Name:
+		util.SetCondition(&tunnelClass.Status.Conditions, tunnelClass.Generation, util.ConditionDefaultConflict, metav1.ConditionFalse,
+			"Selected", "TunnelClass is the default class")
+	default:
+		util.SetCondition(&tunnelClass.Status.Conditions, tunnelClass.Generation, util.ConditionDefaultConflict, metav1.ConditionTrue,
+			"NotSelected", fmt.Sprintf("TunnelClass '%s' is also marked default and is older; PortalExposes without a tunnelClassName use it",
+				selectedDefault))
+	}
 
-			// Remove the annotation
-			delete(tc.Annotations, "portal.gosuda.org/is-default-class")
+	if equality.Semantic.DeepEqual(before, &tunnelClass.Status) {
+		return nil
+	}
 
-			if err := r.Update(ctx, tc); err != nil {
-				return err
-			}
+	// Audit every change to an active override
+	condition := util.FindCondition(tunnelClass.Status.Conditions, util.ConditionRestrictedPodSecurity)
+	previous := util.FindCondition(before.Conditions, util.ConditionRestrictedPodSecurity)
+	if override != nil && (previous == nil || previous.Message != condition.Message) && r.Recorder != nil {
+		r.Recorder.Event(tunnelClass, corev1.EventTypeWarning, util.EventReasonSecurityOverride, condition.Message)
+	}
+	return r.patchStatus(ctx, tunnelClass)
+}
+
+// defaultTunnelClasses maps a TunnelClass gaining, losing or deleted with the default marker
+// to every other default class, so they re-evaluate which one is selected
+func (r *TunnelClassReconciler) defaultTunnelClasses(ctx context.Context, obj client.Object) []reconcile.Request {
+	tunnelClasses := &portalv1alpha1.TunnelClassList{}
+	if err := r.List(ctx, tunnelClasses); err != nil {
+		logf.FromContext(ctx).Error(err, "unable to list TunnelClasses for TunnelClass", "tunnelClass", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, tc := range tunnelclass.DefaultTunnelClasses(tunnelClasses.Items) {
+		if tc.Name != obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(tc)})
 		}
 	}
+	return requests
+}
+
+// tunnelClassesForPortalExpose maps a PortalExpose to the class it names, or to its namespace default and every default class
+// Updates are mapped for both the old and the new object, so a class the PortalExpose leaves is recounted too
+func (r *TunnelClassReconciler) tunnelClassesForPortalExpose(ctx context.Context, obj client.Object) []reconcile.Request {
+	if name := obj.(*portalv1alpha1.PortalExpose).Spec.TunnelClassName; name != "" {
+		return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: name}}}
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: obj.GetNamespace()}, namespace); client.IgnoreNotFound(err) != nil {
+		logf.FromContext(ctx).Error(err, "unable to get Namespace for PortalExpose", "portalExpose", obj.GetName())
+		return nil
+	}
+	return r.defaultTunnelClassesFor(ctx, namespace)
+}
+
+// tunnelClassesForNamespace maps a Namespace to the classes its PortalExposes without a class may use
+// Updates are mapped for both the old and the new object, so the class a namespace stops picking is recounted too
+func (r *TunnelClassReconciler) tunnelClassesForNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.defaultTunnelClassesFor(ctx, obj.(*corev1.Namespace))
+}
+
+// defaultTunnelClassesFor returns requests for the namespace default class and every default class
+func (r *TunnelClassReconciler) defaultTunnelClassesFor(ctx context.Context, namespace *corev1.Namespace) []reconcile.Request {
+	var requests []reconcile.Request
+	if name := namespace.Annotations[tunnelclass.NamespaceDefaultClassAnnotation]; name != "" {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKey{Name: name}})
+	}
 
-	return nil
+	tunnelClasses := &portalv1alpha1.TunnelClassList{}
+	if err := r.List(ctx, tunnelClasses); err != nil {
+		logf.FromContext(ctx).Error(err, "unable to list TunnelClasses for Namespace", "namespace", namespace.Name)
+		return requests
+	}
+	for _, tc := range tunnelclass.DefaultTunnelClasses(tunnelClasses.Items) {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(tc)})
+	}
+	return requests
 }
 
 // SetupWithManager sets up the controller with the Manager.
+// Requires the field indexes registered by SetupIndexes.
 func (r *TunnelClassReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&portalv1alpha1.TunnelClass{}).
+		Watches(&portalv1alpha1.TunnelClass{},
+			handler.EnqueueRequestsFromMapFunc(r.defaultTunnelClasses),
+			// Creates, deletes and annotation changes can move the default to another class
+			builder.WithPredicates(predicate.AnnotationChangedPredicate{})).
+		Watches(&portalv1alpha1.PortalExpose{},
+			handler.EnqueueRequestsFromMapFunc(r.tunnelClassesForPortalExpose),
+			// Recount on create, delete and spec.tunnelClassName changes, not on status updates
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.tunnelClassesForNamespace),
+			// Recount when a namespace picks another default class
+			builder.WithPredicates(predicate.AnnotationChangedPredicate{})).
 		Named("tunnelclass").
 		Complete(r)
 }
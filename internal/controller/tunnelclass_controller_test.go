@@ -37,8 +37,7 @@ var _ = Describe("TunnelClass Controller", func() {
 		ctx := context.Background()
 
 		typeNamespacedName := types.NamespacedName{
-			Name:      resourceName,
-			Namespace: "default", // TODO(user):Modify as needed
+			Name: resourceName, // TunnelClass is cluster-scoped
 		}
 		tunnelclass := &portalv1alpha1.TunnelClass{}
 
@@ -48,8 +47,7 @@ var _ = Describe("TunnelClass Controller", func() {
 			if err != nil && errors.IsNotFound(err) {
 				resource := &portalv1alpha1.TunnelClass{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      resourceName,
-						Namespace: "default",
+						Name: resourceName,
 					},
 					Spec: portalv1alpha1.TunnelClassSpec{
 						Replicas: 1,
@@ -71,9 +69,12 @@ var _ = Describe("TunnelClass Controller", func() {
 		})
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
+			Eventually(func() error {
+				return mgrClient.Get(ctx, typeNamespacedName, &portalv1alpha1.TunnelClass{})
+			}).Should(Succeed())
 			controllerReconciler := &TunnelClassReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client: mgrClient,
+				Scheme: mgrClient.Scheme(),
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
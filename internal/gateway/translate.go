@@ -0,0 +1,280 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gateway
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/autoexpose"
+	"github.com/gosuda/portal-expose/internal/util"
+)
+
+const (
+	// RouteLabel records the HTTPRoute a PortalExpose was generated for
+	RouteLabel = "portal.gosuda.org/httproute"
+
+	// ConditionTunnelReady is the implementation-specific route condition mirroring the PortalExpose phase
+	ConditionTunnelReady = "portal.gosuda.org/TunnelReady"
+)
+
+// ConditionError is a translation failure reported as a status condition reason
+type ConditionError struct {
+	Reason  string
+	Message string
+}
+
+func (e *ConditionError) Error() string {
+	return e.Message
+}
+
+// ParentGateway returns the Gateway a route parentRef points at
+// It returns false for parents of any other kind
+func ParentGateway(route *HTTPRoute, ref ParentReference) (types.NamespacedName, bool) {
+	if ref.Group != nil && *ref.Group != Group {
+		return types.NamespacedName{}, false
+	}
+	if ref.Kind != nil && *ref.Kind != GatewayGVK.Kind {
+		return types.NamespacedName{}, false
+	}
+
+	namespace := route.Namespace
+	if ref.Namespace != nil && *ref.Namespace != "" {
+		namespace = *ref.Namespace
+	}
+	return types.NamespacedName{Namespace: namespace, Name: ref.Name}, true
+}
+
+// RelayTargets returns the relays a Gateway publishes on: its portal.gosuda.org/relay
+// annotation, or else the ClusterRelay referenced by its GatewayClass parametersRef
+func RelayTargets(gateway *Gateway, class *GatewayClass) ([]portalv1alpha1.RelayTarget, error) {
+	if value, ok := gateway.Annotations[autoexpose.RelayAnnotation]; ok {
+		targets, err := autoexpose.ParseRelayTargets(value)
+		if err != nil {
+			return nil, &ConditionError{Reason: "InvalidParameters", Message: err.Error()}
+		}
+		return targets, nil
+	}
+
+	ref := class.Spec.ParametersRef
+	if ref == nil {
+		return nil, &ConditionError{
+			Reason: "InvalidParameters",
+			Message: fmt.Sprintf("set the %s annotation on the Gateway or point GatewayClass '%s' parametersRef at a ClusterRelay",
+				autoexpose.RelayAnnotation, class.Name),
+		}
+	}
+	if ref.Group != portalv1alpha1.GroupVersion.Group || ref.Kind != "ClusterRelay" {
+		return nil, &ConditionError{
+			Reason:  "InvalidParameters",
+			Message: fmt.Sprintf("GatewayClass parametersRef must reference a %s ClusterRelay", portalv1alpha1.GroupVersion.Group),
+		}
+	}
+	return []portalv1alpha1.RelayTarget{
+		{Name: ref.Name, RelayRef: &portalv1alpha1.ClusterRelayReference{Name: ref.Name}},
+	}, nil
+}
+
+// Backend returns the single Service backend of the route
+// Tunnels forward every request to one Service port, so all rules must agree on it
+func Backend(route *HTTPRoute) (BackendRef, error) {
+	var backend *BackendRef
+	for _, rule := range route.Spec.Rules {
+		for i := range rule.BackendRefs {
+			ref := rule.BackendRefs[i]
+			if (ref.Group != nil && *ref.Group != "") || (ref.Kind != nil && *ref.Kind != "Service") {
+				return BackendRef{}, &ConditionError{
+					Reason:  "InvalidKind",
+					Message: fmt.Sprintf("backendRef '%s' must reference a Service", ref.Name),
+				}
+			}
+			if ref.Namespace != nil && *ref.Namespace != route.Namespace {
+				return BackendRef{}, &ConditionError{
+					Reason:  "RefNotPermitted",
+					Message: fmt.Sprintf("backendRef '%s' must be in the route namespace", ref.Name),
+				}
+			}
+			if ref.Port == nil {
+				return BackendRef{}, &ConditionError{
+					Reason:  "UnsupportedValue",
+					Message: fmt.Sprintf("backendRef '%s' must set a port", ref.Name),
+				}
+			}
+			if backend == nil {
+				backend = &ref
+				continue
+			}
+			if backend.Name != ref.Name || *backend.Port != *ref.Port {
+				return BackendRef{}, &ConditionError{
+					Reason:  "UnsupportedValue",
+					Message: "all backendRefs must reference the same Service port; tunnels do not split traffic",
+				}
+			}
+		}
+	}
+
+	if backend == nil {
+		return BackendRef{}, &ConditionError{Reason: "BackendNotFound", Message: "route has no backendRefs"}
+	}
+	return *backend, nil
+}
+
+// PortalExposeName returns the name of the PortalExpose generated for a route
+// The suffix keeps it apart from the PortalExpose of an annotated Service or an Ingress of the same name
+func PortalExposeName(route *HTTPRoute) string {
+	return route.Name + "-httproute"
+}
+
+// BuildPortalExpose generates the PortalExpose publishing the route
+// Every hostname is published under its first DNS label, see autoexpose.HostAppName; the first hostname
+// becomes spec.app, every further hostname an endpoint on the same backend
+func BuildPortalExpose(route *HTTPRoute, backend BackendRef, targets []portalv1alpha1.RelayTarget) (*portalv1alpha1.PortalExpose, error) {
+	service := portalv1alpha1.ServiceRef{
+		Name: backend.Name,
+		Port: intstr.FromInt32(*backend.Port),
+	}
+
+	appName := route.Name
+	var endpoints []portalv1alpha1.EndpointSpec
+	hostnamesByAppName := make(map[string]string, len(route.Spec.Hostnames))
+	for i, hostname := range route.Spec.Hostnames {
+		hostAppName := autoexpose.HostAppName(hostname)
+		if hostAppName == "" {
+			return nil, &ConditionError{
+				Reason:  "UnsupportedValue",
+				Message: fmt.Sprintf("hostname '%s' cannot be published; wildcard hostnames are not supported", hostname),
+			}
+		}
+		if other, ok := hostnamesByAppName[hostAppName]; ok {
+			if other == hostname {
+				continue
+			}
+			return nil, &ConditionError{
+				Reason: "UnsupportedValue",
+				Message: fmt.Sprintf("hostnames '%s' and '%s' would both be published as '%s'; the first DNS labels of the hostnames must differ",
+					other, hostname, hostAppName),
+			}
+		}
+		hostnamesByAppName[hostAppName] = hostname
+
+		if i == 0 {
+			appName = hostAppName
+			continue
+		}
+		endpoints = append(endpoints, portalv1alpha1.EndpointSpec{
+			Name:      autoexpose.HostEndpointName(hostname, hostAppName),
+			Subdomain: hostAppName,
+			Service:   service,
+		})
+	}
+
+	return &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      PortalExposeName(route),
+			Namespace: route.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "portal-expose-controller",
+				RouteLabel:                     route.Name,
+			},
+		},
+		Spec: portalv1alpha1.PortalExposeSpec{
+			App: portalv1alpha1.AppSpec{
+				Name:    appName,
+				Service: service,
+			},
+			Endpoints: endpoints,
+			Relay: portalv1alpha1.RelaySpec{
+				Targets: targets,
+			},
+		},
+	}, nil
+}
+
+// SetRouteConditions updates the route parent conditions from the generated PortalExpose
+func SetRouteConditions(conditions *[]metav1.Condition, generation int64, portalExpose *portalv1alpha1.PortalExpose) {
+	set := func(conditionType string, status metav1.ConditionStatus, reason, message string) {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               conditionType,
+			Status:             status,
+			ObservedGeneration: generation,
+			Reason:             reason,
+			Message:            message,
+		})
+	}
+
+	if conflict := meta.FindStatusCondition(portalExpose.Status.Conditions, util.ConditionNameConflict); conflict != nil &&
+		conflict.Status == metav1.ConditionTrue {
+		set("Accepted", metav1.ConditionFalse, "NameConflict", conflict.Message)
+	} else {
+		set("Accepted", metav1.ConditionTrue, "Accepted", "Route is published through PortalExpose "+portalExpose.Name)
+	}
+
+	resolvedRefs := true
+	for _, conditionType := range []string{util.ConditionServiceExists, util.ConditionServicePortResolved} {
+		if condition := meta.FindStatusCondition(portalExpose.Status.Conditions, conditionType); condition != nil &&
+			condition.Status == metav1.ConditionFalse {
+			set("ResolvedRefs", metav1.ConditionFalse, "BackendNotFound", condition.Message)
+			resolvedRefs = false
+			break
+		}
+	}
+	if resolvedRefs {
+		set("ResolvedRefs", metav1.ConditionTrue, "ResolvedRefs", "Backend resolved")
+	}
+
+	phase := portalExpose.Status.Phase
+	if phase == "" {
+		phase = util.PhasePending
+	}
+	message := "Tunnel is " + phase
+	if portalExpose.Status.PublicURL != "" {
+		message += ", published at " + portalExpose.Status.PublicURL
+	}
+	if phase == util.PhaseReady {
+		set(ConditionTunnelReady, metav1.ConditionTrue, phase, message)
+	} else {
+		set(ConditionTunnelReady, metav1.ConditionFalse, phase, message)
+	}
+}
+
+// SetErrorConditions updates the route parent conditions of a route that could not be translated
+// Backend errors keep the route Accepted and fail ResolvedRefs; any other error fails Accepted
+func SetErrorConditions(conditions *[]metav1.Condition, generation int64, err *ConditionError) {
+	set := func(conditionType string, status metav1.ConditionStatus, reason, message string) {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               conditionType,
+			Status:             status,
+			ObservedGeneration: generation,
+			Reason:             reason,
+			Message:            message,
+		})
+	}
+
+	switch err.Reason {
+	case "InvalidKind", "RefNotPermitted", "BackendNotFound":
+		set("Accepted", metav1.ConditionTrue, "Accepted", "Route is attached")
+		set("ResolvedRefs", metav1.ConditionFalse, err.Reason, err.Message)
+	default:
+		set("Accepted", metav1.ConditionFalse, err.Reason, err.Message)
+	}
+	meta.RemoveStatusCondition(conditions, ConditionTunnelReady)
+}
@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/util"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func testRoute(mutate func(*HTTPRoute)) *HTTPRoute {
+	route := &HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec: HTTPRouteSpec{
+			ParentRefs: []ParentReference{{Name: "portal"}},
+			Hostnames:  []string{"my-app.portal.gosuda.org", "my-app-alt.portal.gosuda.org"},
+			Rules: []HTTPRouteRule{
+				{BackendRefs: []BackendRef{{Name: "web-svc", Port: ptr(int32(8080))}}},
+			},
+		},
+	}
+	if mutate != nil {
+		mutate(route)
+	}
+	return route
+}
+
+func TestParentGateway(t *testing.T) {
+	route := testRoute(nil)
+
+	key, ok := ParentGateway(route, ParentReference{Name: "portal"})
+	if !ok || key != (types.NamespacedName{Namespace: "apps", Name: "portal"}) {
+		t.Errorf("ParentGateway() = %v, %v, want apps/portal", key, ok)
+	}
+
+	key, ok = ParentGateway(route, ParentReference{Name: "portal", Namespace: ptr("infra")})
+	if !ok || key.Namespace != "infra" {
+		t.Errorf("ParentGateway() = %v, want the parentRef namespace", key)
+	}
+
+	if _, ok := ParentGateway(route, ParentReference{Name: "svc", Kind: ptr("Service"), Group: ptr("")}); ok {
+		t.Error("ParentGateway() accepted a non-Gateway parent")
+	}
+}
+
+func TestRelayTargets(t *testing.T) {
+	class := &GatewayClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "portal"},
+		Spec: GatewayClassSpec{
+			ControllerName: ControllerName,
+			ParametersRef:  &ParametersReference{Group: "portal.gosuda.org", Kind: "ClusterRelay", Name: "shared-relay"},
+		},
+	}
+
+	targets, err := RelayTargets(&Gateway{}, class)
+	if err != nil || len(targets) != 1 || targets[0].RelayRef == nil || targets[0].RelayRef.Name != "shared-relay" {
+		t.Errorf("RelayTargets() = %+v, %v, want the class ClusterRelay", targets, err)
+	}
+
+	gw := &Gateway{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{"portal.gosuda.org/relay": "wss://portal.gosuda.org/relay"},
+	}}
+	targets, err = RelayTargets(gw, class)
+	if err != nil || len(targets) != 1 || targets[0].URL != "wss://portal.gosuda.org/relay" {
+		t.Errorf("RelayTargets() = %+v, %v, want the Gateway annotation to win", targets, err)
+	}
+
+	class.Spec.ParametersRef = nil
+	var conditionErr *ConditionError
+	if _, err := RelayTargets(&Gateway{}, class); !errors.As(err, &conditionErr) || conditionErr.Reason != "InvalidParameters" {
+		t.Errorf("RelayTargets() error = %v, want InvalidParameters", err)
+	}
+}
+
+func TestBackend(t *testing.T) {
+	tests := []struct {
+		name       string
+		route      *HTTPRoute
+		wantReason string
+	}{
+		{name: "Single Service", route: testRoute(nil)},
+		{
+			name: "Same backend in every rule",
+			route: testRoute(func(r *HTTPRoute) {
+				r.Spec.Rules = append(r.Spec.Rules, r.Spec.Rules[0])
+			}),
+		},
+		{
+			name: "Traffic split",
+			route: testRoute(func(r *HTTPRoute) {
+				r.Spec.Rules[0].BackendRefs = append(r.Spec.Rules[0].BackendRefs, BackendRef{Name: "canary", Port: ptr(int32(8080))})
+			}),
+			wantReason: "UnsupportedValue",
+		},
+		{
+			name: "Cross-namespace backend",
+			route: testRoute(func(r *HTTPRoute) {
+				r.Spec.Rules[0].BackendRefs[0].Namespace = ptr("other")
+			}),
+			wantReason: "RefNotPermitted",
+		},
+		{
+			name: "Non-Service backend",
+			route: testRoute(func(r *HTTPRoute) {
+				r.Spec.Rules[0].BackendRefs[0].Kind = ptr("ServiceImport")
+			}),
+			wantReason: "InvalidKind",
+		},
+		{
+			name: "No backend",
+			route: testRoute(func(r *HTTPRoute) {
+				r.Spec.Rules = nil
+			}),
+			wantReason: "BackendNotFound",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, err := Backend(tt.route)
+			if tt.wantReason == "" {
+				if err != nil || backend.Name != "web-svc" {
+					t.Errorf("Backend() = %+v, %v, want web-svc", backend, err)
+				}
+				return
+			}
+			var conditionErr *ConditionError
+			if !errors.As(err, &conditionErr) || conditionErr.Reason != tt.wantReason {
+				t.Errorf("Backend() error = %v, want reason %s", err, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestBuildPortalExpose(t *testing.T) {
+	route := testRoute(nil)
+	backend, _ := Backend(route)
+	targets := []portalv1alpha1.RelayTarget{{Name: "gosuda", URL: "wss://portal.gosuda.org/relay"}}
+
+	portalExpose, err := BuildPortalExpose(route, backend, targets)
+	if err != nil {
+		t.Fatalf("BuildPortalExpose() error = %v", err)
+	}
+	if portalExpose.Name != "web-httproute" || portalExpose.Namespace != "apps" {
+		t.Errorf("BuildPortalExpose() key = %s/%s, want apps/web-httproute", portalExpose.Namespace, portalExpose.Name)
+	}
+	if portalExpose.Spec.App.Name != "my-app" || portalExpose.Spec.App.Service.Port != intstr.FromInt32(8080) {
+		t.Errorf("BuildPortalExpose() app = %+v, want my-app on web-svc:8080", portalExpose.Spec.App)
+	}
+	if len(portalExpose.Spec.Endpoints) != 1 || portalExpose.Spec.Endpoints[0].Name != "my-app-alt" ||
+		portalExpose.Spec.Endpoints[0].Subdomain != "my-app-alt" {
+		t.Errorf("BuildPortalExpose() endpoints = %+v, want my-app-alt", portalExpose.Spec.Endpoints)
+	}
+
+	route.Spec.Hostnames = []string{"api.a.example.com", "api.b.example.com"}
+	if _, err := BuildPortalExpose(route, backend, targets); err == nil {
+		t.Error("BuildPortalExpose() expected error for hostnames sharing the first DNS label")
+	}
+
+	route.Spec.Hostnames = []string{"*.portal.gosuda.org"}
+	if _, err := BuildPortalExpose(route, backend, targets); err == nil {
+		t.Error("BuildPortalExpose() expected error for a wildcard hostname")
+	}
+
+	route.Spec.Hostnames = nil
+	portalExpose, err = BuildPortalExpose(route, backend, targets)
+	if err != nil || portalExpose.Spec.App.Name != "web" {
+		t.Errorf("BuildPortalExpose() app name = %v, want the route name without hostnames", portalExpose.Spec.App.Name)
+	}
+}
+
+func TestSetRouteConditions(t *testing.T) {
+	portalExpose := &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{Name: "web"},
+		Status: portalv1alpha1.PortalExposeStatus{
+			Phase:     util.PhaseReady,
+			PublicURL: "https://my-app.portal.gosuda.org",
+		},
+	}
+
+	var conditions []metav1.Condition
+	SetRouteConditions(&conditions, 3, portalExpose)
+	for _, conditionType := range []string{"Accepted", "ResolvedRefs", ConditionTunnelReady} {
+		condition := meta.FindStatusCondition(conditions, conditionType)
+		if condition == nil || condition.Status != metav1.ConditionTrue || condition.ObservedGeneration != 3 {
+			t.Errorf("Condition %s = %+v, want True at generation 3", conditionType, condition)
+		}
+	}
+
+	portalExpose.Status.Phase = util.PhaseFailed
+	meta.SetStatusCondition(&portalExpose.Status.Conditions, metav1.Condition{
+		Type: util.ConditionServicePortResolved, Status: metav1.ConditionFalse, Reason: "ServicePortNotFound", Message: "port 8080 not found",
+	})
+	SetRouteConditions(&conditions, 3, portalExpose)
+	if condition := meta.FindStatusCondition(conditions, "ResolvedRefs"); condition.Status != metav1.ConditionFalse ||
+		condition.Reason != "BackendNotFound" {
+		t.Errorf("ResolvedRefs = %+v, want BackendNotFound", condition)
+	}
+	if condition := meta.FindStatusCondition(conditions, ConditionTunnelReady); condition.Status != metav1.ConditionFalse {
+		t.Errorf("TunnelReady = %+v, want False", condition)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gateway translates Gateway API resources into PortalExposes.
+//
+// Gateway API objects are read as unstructured objects and decoded into the
+// subset of gateway.networking.k8s.io/v1 declared here, so the controller does
+// not depend on the Gateway API Go module and runs on clusters without its CRDs
+// when the integration is disabled.
+//
+// The types are not CRDs of this project, so the CRD generator skips the package.
+// +kubebuilder:skip
+package gateway
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// ControllerName is the GatewayClass spec.controllerName handled by portal-expose
+	ControllerName = "portal.gosuda.org/gateway-controller"
+
+	// Group is the Gateway API group
+	Group = "gateway.networking.k8s.io"
+)
+
+var (
+	// GatewayClassGVK is the GroupVersionKind of GatewayClass
+	GatewayClassGVK = schema.GroupVersionKind{Group: Group, Version: "v1", Kind: "GatewayClass"}
+
+	// GatewayGVK is the GroupVersionKind of Gateway
+	GatewayGVK = schema.GroupVersionKind{Group: Group, Version: "v1", Kind: "Gateway"}
+
+	// HTTPRouteGVK is the GroupVersionKind of HTTPRoute
+	HTTPRouteGVK = schema.GroupVersionKind{Group: Group, Version: "v1", Kind: "HTTPRoute"}
+)
+
+// GatewayClass is the subset of a Gateway API GatewayClass used by portal-expose
+type GatewayClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GatewayClassSpec `json:"spec"`
+}
+
+// GatewayClassSpec is the subset of the GatewayClass spec used by portal-expose
+type GatewayClassSpec struct {
+	ControllerName string               `json:"controllerName"`
+	ParametersRef  *ParametersReference `json:"parametersRef,omitempty"`
+}
+
+// GatewayClassStatus is the GatewayClass status written by portal-expose
+type GatewayClassStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ParametersReference points a GatewayClass at its configuration
+// portal-expose accepts a ClusterRelay as the default relay of the class
+type ParametersReference struct {
+	Group     string  `json:"group"`
+	Kind      string  `json:"kind"`
+	Name      string  `json:"name"`
+	Namespace *string `json:"namespace,omitempty"`
+}
+
+// Gateway is the subset of a Gateway API Gateway used by portal-expose
+type Gateway struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GatewaySpec `json:"spec"`
+}
+
+// GatewaySpec is the subset of the Gateway spec used by portal-expose
+type GatewaySpec struct {
+	GatewayClassName string     `json:"gatewayClassName"`
+	Listeners        []Listener `json:"listeners,omitempty"`
+}
+
+// Listener is the subset of a Gateway listener used by portal-expose
+type Listener struct {
+	Name     string  `json:"name"`
+	Hostname *string `json:"hostname,omitempty"`
+	Port     int32   `json:"port"`
+	Protocol string  `json:"protocol"`
+}
+
+// GatewayStatus is the Gateway status written by portal-expose
+type GatewayStatus struct {
+	Addresses  []GatewayStatusAddress `json:"addresses,omitempty"`
+	Conditions []metav1.Condition     `json:"conditions,omitempty"`
+	Listeners  []ListenerStatus       `json:"listeners,omitempty"`
+}
+
+// GatewayStatusAddress is an address the Gateway is reachable at
+type GatewayStatusAddress struct {
+	Type  *string `json:"type,omitempty"`
+	Value string  `json:"value"`
+}
+
+// ListenerStatus is the status of one Gateway listener
+type ListenerStatus struct {
+	Name           string             `json:"name"`
+	SupportedKinds []RouteGroupKind   `json:"supportedKinds"`
+	AttachedRoutes int32              `json:"attachedRoutes"`
+	Conditions     []metav1.Condition `json:"conditions"`
+}
+
+// RouteGroupKind names a route kind a listener accepts
+type RouteGroupKind struct {
+	Group *string `json:"group,omitempty"`
+	Kind  string  `json:"kind"`
+}
+
+// HTTPRoute is the subset of a Gateway API HTTPRoute used by portal-expose
+type HTTPRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HTTPRouteSpec   `json:"spec"`
+	Status HTTPRouteStatus `json:"status,omitempty"`
+}
+
+// HTTPRouteSpec is the subset of the HTTPRoute spec used by portal-expose
+type HTTPRouteSpec struct {
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+	Hostnames  []string          `json:"hostnames,omitempty"`
+	Rules      []HTTPRouteRule   `json:"rules,omitempty"`
+}
+
+// ParentReference identifies the Gateway a route attaches to
+type ParentReference struct {
+	Group       *string `json:"group,omitempty"`
+	Kind        *string `json:"kind,omitempty"`
+	Namespace   *string `json:"namespace,omitempty"`
+	Name        string  `json:"name"`
+	SectionName *string `json:"sectionName,omitempty"`
+	Port        *int32  `json:"port,omitempty"`
+}
+
+// HTTPRouteRule is the subset of an HTTPRoute rule used by portal-expose
+type HTTPRouteRule struct {
+	BackendRefs []BackendRef `json:"backendRefs,omitempty"`
+}
+
+// BackendRef identifies the backend a rule forwards to
+type BackendRef struct {
+	Group     *string `json:"group,omitempty"`
+	Kind      *string `json:"kind,omitempty"`
+	Name      string  `json:"name"`
+	Namespace *string `json:"namespace,omitempty"`
+	Port      *int32  `json:"port,omitempty"`
+	Weight    *int32  `json:"weight,omitempty"`
+}
+
+// HTTPRouteStatus is the HTTPRoute status shared between Gateway controllers
+type HTTPRouteStatus struct {
+	Parents []RouteParentStatus `json:"parents,omitempty"`
+}
+
+// RouteParentStatus is the status of a route for one parent Gateway
+type RouteParentStatus struct {
+	ParentRef      ParentReference    `json:"parentRef"`
+	ControllerName string             `json:"controllerName"`
+	Conditions     []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// NewUnstructured returns an empty unstructured object of the given kind
+func NewUnstructured(gvk schema.GroupVersionKind) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	return u
+}
+
+// NewUnstructuredList returns an empty unstructured list of the given kind
+func NewUnstructuredList(gvk schema.GroupVersionKind) *unstructured.UnstructuredList {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	return list
+}
+
+// FromUnstructured decodes an unstructured Gateway API object into one of the types above
+func FromUnstructured(u *unstructured.Unstructured, obj any) error {
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj)
+}
+
+// SetStatus replaces the status of an unstructured object with a pointer to one of the status types above
+func SetStatus(u *unstructured.Unstructured, status any) error {
+	statusMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+	if err != nil {
+		return err
+	}
+	return unstructured.SetNestedField(u.Object, statusMap, "status")
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+// PrimaryEndpointName is the endpoint name of spec.app
+const PrimaryEndpointName = "app"
+
+// AppEndpoint is one endpoint published by the tunnel Deployment
+type AppEndpoint struct {
+	// Name is PrimaryEndpointName for spec.app, else spec.endpoints[].name
+	Name string
+
+	// Subdomain is the name the endpoint is published under on every relay
+	Subdomain string
+
+	// Service is the Service the endpoint forwards to
+	Service portalv1alpha1.ServiceRef
+
+	// ServicePort is Service.Port resolved against the Service
+	// Set by the controller before building the Deployment
+	ServicePort int32
+
+	// ServiceSelector and TargetPort locate the pods and port behind ServicePort
+	// Set by the controller together with ServicePort; a nil selector means the Service has none
+	ServiceSelector map[string]string
+	TargetPort      intstr.IntOrString
+}
+
+// AppEndpoints returns spec.app followed by spec.endpoints, in order
+func AppEndpoints(portalExpose *portalv1alpha1.PortalExpose) []AppEndpoint {
+	endpoints := make([]AppEndpoint, 0, 1+len(portalExpose.Spec.Endpoints))
+	endpoints = append(endpoints, AppEndpoint{
+		Name:      PrimaryEndpointName,
+		Subdomain: portalExpose.Spec.App.Name,
+		Service:   portalExpose.Spec.App.Service,
+	})
+	for _, endpoint := range portalExpose.Spec.Endpoints {
+		endpoints = append(endpoints, AppEndpoint{
+			Name:      endpoint.Name,
+			Subdomain: endpoint.Subdomain,
+			Service:   endpoint.Service,
+		})
+	}
+	return endpoints
+}
+
+// Subdomains returns the subdomains claimed by the PortalExpose
+func Subdomains(portalExpose *portalv1alpha1.PortalExpose) []string {
+	endpoints := AppEndpoints(portalExpose)
+	subdomains := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		subdomains = append(subdomains, endpoint.Subdomain)
+	}
+	return subdomains
+}
+
+// ComputeEndpointStatuses reports the public URL of every endpoint on the given relay
+func ComputeEndpointStatuses(endpoints []AppEndpoint, relay RelayEndpoint) []portalv1alpha1.EndpointStatus {
+	statuses := make([]portalv1alpha1.EndpointStatus, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		statuses = append(statuses, portalv1alpha1.EndpointStatus{
+			Name:      endpoint.Name,
+			PublicURL: relay.PublicURL(endpoint.Subdomain),
+		})
+	}
+	return statuses
+}
@@ -0,0 +1,37 @@
+package tunnel
+
+import (
+	"testing"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestComputeEndpointStatuses(t *testing.T) {
+	portalExpose := &portalv1alpha1.PortalExpose{
+		Spec: portalv1alpha1.PortalExposeSpec{
+			App: portalv1alpha1.AppSpec{
+				Name:    "my-app",
+				Service: portalv1alpha1.ServiceRef{Name: "web", Port: intstr.FromInt32(80)},
+			},
+			Endpoints: []portalv1alpha1.EndpointSpec{
+				{Name: "admin", Subdomain: "my-app-admin", Service: portalv1alpha1.ServiceRef{Name: "web", Port: intstr.FromString("admin")}},
+			},
+		},
+	}
+	relay := RelayEndpoint{Name: "gosuda", URL: "wss://portal.gosuda.org/relay", PublicDomain: "portal.gosuda.org"}
+
+	statuses := ComputeEndpointStatuses(AppEndpoints(portalExpose), relay)
+	expected := []portalv1alpha1.EndpointStatus{
+		{Name: PrimaryEndpointName, PublicURL: "https://my-app.portal.gosuda.org"},
+		{Name: "admin", PublicURL: "https://my-app-admin.portal.gosuda.org"},
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("Expected %d statuses, got %d", len(expected), len(statuses))
+	}
+	for i := range expected {
+		if statuses[i] != expected[i] {
+			t.Errorf("Status[%d] = %+v, want %+v", i, statuses[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+const (
+	// ConnectionsMetricName is the pod metric counting the connections a tunnel pod proxies
+	// It reaches the HPA through a custom metrics adapter
+	ConnectionsMetricName = "portal_tunnel_active_connections"
+
+	// DefaultTargetCPUUtilization is the CPU target of autoscaled tunnels without an explicit target
+	DefaultTargetCPUUtilization int32 = 80
+)
+
+// BuildHorizontalPodAutoscaler creates the HorizontalPodAutoscaler scaling the Deployment of BuildDeployment
+// It returns nil when the TunnelClass does not enable autoscaling
+func BuildHorizontalPodAutoscaler(
+	portalExpose *portalv1alpha1.PortalExpose,
+	tunnelClass *portalv1alpha1.TunnelClass,
+) *autoscalingv2.HorizontalPodAutoscaler {
+	autoscaling := tunnelClass.Spec.Autoscaling
+	if autoscaling == nil {
+		return nil
+	}
+	name := portalExpose.Name + "-tunnel"
+
+	var metrics []autoscalingv2.MetricSpec
+	targetCPU := autoscaling.TargetCPUUtilizationPercentage
+	if targetCPU == nil && autoscaling.TargetConnectionsPerPod == nil {
+		targetCPU = ptr.To(DefaultTargetCPUUtilization)
+	}
+	if targetCPU != nil {
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: corev1.ResourceCPU,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: targetCPU,
+				},
+			},
+		})
+	}
+	if autoscaling.TargetConnectionsPerPod != nil {
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.PodsMetricSourceType,
+			Pods: &autoscalingv2.PodsMetricSource{
+				Metric: autoscalingv2.MetricIdentifier{Name: ConnectionsMetricName},
+				Target: autoscalingv2.MetricTarget{
+					Type:         autoscalingv2.AverageValueMetricType,
+					AverageValue: resource.NewQuantity(int64(*autoscaling.TargetConnectionsPerPod), resource.DecimalSI),
+				},
+			},
+		})
+	}
+
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: portalExpose.Namespace,
+			Labels:    PodLabels(portalExpose),
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       name,
+			},
+			MinReplicas: ptr.To(autoscaling.MinReplicas),
+			MaxReplicas: autoscaling.MaxReplicas,
+			Metrics:     metrics,
+		},
+	}
+}
@@ -0,0 +1,97 @@
+package tunnel
+
+import (
+	"testing"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+func TestBuildHorizontalPodAutoscaler(t *testing.T) {
+	portalExpose := &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+	}
+
+	tests := []struct {
+		name        string
+		autoscaling *portalv1alpha1.TunnelAutoscaling
+		wantNil     bool
+		wantCPU     int32
+		wantPods    int64
+	}{
+		{
+			name:    "Autoscaling disabled",
+			wantNil: true,
+		},
+		{
+			name:        "Default CPU target",
+			autoscaling: &portalv1alpha1.TunnelAutoscaling{MinReplicas: 2, MaxReplicas: 10},
+			wantCPU:     DefaultTargetCPUUtilization,
+		},
+		{
+			name:        "Connections only",
+			autoscaling: &portalv1alpha1.TunnelAutoscaling{MinReplicas: 2, MaxReplicas: 10, TargetConnectionsPerPod: ptr.To(int32(500))},
+			wantPods:    500,
+		},
+		{
+			name: "CPU and connections",
+			autoscaling: &portalv1alpha1.TunnelAutoscaling{
+				MinReplicas:                    2,
+				MaxReplicas:                    10,
+				TargetCPUUtilizationPercentage: ptr.To(int32(60)),
+				TargetConnectionsPerPod:        ptr.To(int32(500)),
+			},
+			wantCPU:  60,
+			wantPods: 500,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				Spec: portalv1alpha1.TunnelClassSpec{Replicas: 3, Size: "small", Autoscaling: tt.autoscaling},
+			}
+			hpa := BuildHorizontalPodAutoscaler(portalExpose, tunnelClass)
+			if tt.wantNil {
+				if hpa != nil {
+					t.Errorf("BuildHorizontalPodAutoscaler() = %v, want nil", hpa)
+				}
+				return
+			}
+
+			if hpa.Spec.ScaleTargetRef.Kind != "Deployment" || hpa.Spec.ScaleTargetRef.Name != "test-app-tunnel" {
+				t.Errorf("ScaleTargetRef = %+v, want Deployment test-app-tunnel", hpa.Spec.ScaleTargetRef)
+			}
+			if *hpa.Spec.MinReplicas != tt.autoscaling.MinReplicas || hpa.Spec.MaxReplicas != tt.autoscaling.MaxReplicas {
+				t.Errorf("Replicas = %d-%d, want %d-%d", *hpa.Spec.MinReplicas, hpa.Spec.MaxReplicas,
+					tt.autoscaling.MinReplicas, tt.autoscaling.MaxReplicas)
+			}
+
+			var gotCPU int32
+			var gotPods int64
+			for _, metric := range hpa.Spec.Metrics {
+				switch {
+				case metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource.Name == corev1.ResourceCPU:
+					gotCPU = *metric.Resource.Target.AverageUtilization
+				case metric.Type == autoscalingv2.PodsMetricSourceType && metric.Pods.Metric.Name == ConnectionsMetricName:
+					gotPods = metric.Pods.Target.AverageValue.Value()
+				}
+			}
+			if gotCPU != tt.wantCPU {
+				t.Errorf("CPU target = %d, want %d", gotCPU, tt.wantCPU)
+			}
+			if gotPods != tt.wantPods {
+				t.Errorf("Connections target = %d, want %d", gotPods, tt.wantPods)
+			}
+
+			deployment := BuildDeployment(portalExpose, tunnelClass, nil, nil, DefaultSizes()["small"])
+			if *deployment.Spec.Replicas != tt.autoscaling.MinReplicas {
+				t.Errorf("Deployment replicas = %d, want minReplicas %d", *deployment.Spec.Replicas, tt.autoscaling.MinReplicas)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+const (
+	// CredentialsHashAnnotation records the hash of the relay credential Secrets on the pod template,
+	// so rotating a Secret rolls the tunnel pods
+	CredentialsHashAnnotation = "portal.gosuda.org/credentials-hash"
+
+	// CredentialsMountPath is the directory relay credential Secrets are mounted under, one directory per relay target
+	CredentialsMountPath = "/etc/portal/relays"
+
+	// SecretLabel marks the Secrets mounted by tunnel pods, the identities and the relay credentials
+	// The controller only caches Secrets carrying it
+	SecretLabel = "portal.gosuda.org/tunnel-secret"
+
+	// secretVolumeMode keeps mounted Secrets readable by the non-root tunnel user
+	// It is set explicitly so the API server default does not differ from the desired pod template
+	secretVolumeMode int32 = 0o444
+)
+
+// CredentialsSecretNames returns the distinct names of the Secrets referenced by the relays, in order
+func CredentialsSecretNames(relays []RelayEndpoint) []string {
+	var names []string
+	for _, relay := range relays {
+		if relay.Credentials != nil && !slices.Contains(names, relay.Credentials.Name) {
+			names = append(names, relay.Credentials.Name)
+		}
+	}
+	return names
+}
+
+// CredentialsHash hashes the name and data of the relay credential Secrets
+// It returns "" when there are no Secrets
+func CredentialsHash(secrets []*corev1.Secret) string {
+	if len(secrets) == 0 {
+		return ""
+	}
+	sorted := slices.Clone(secrets)
+	slices.SortFunc(sorted, func(a, b *corev1.Secret) int { return strings.Compare(a.Name, b.Name) })
+
+	h := sha256.New()
+	for _, secret := range sorted {
+		fmt.Fprintf(h, "%s\x00", secret.Name)
+		keys := make([]string, 0, len(secret.Data))
+		for key := range secret.Data {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			fmt.Fprintf(h, "%s\x00%d\x00", key, len(secret.Data[key]))
+			h.Write(secret.Data[key])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SetCredentialsHash stamps the credentials hash on the pod template
+func SetCredentialsHash(template *corev1.PodTemplateSpec, hash string) {
+	if hash == "" {
+		return
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[CredentialsHashAnnotation] = hash
+}
+
+// CredentialsEnvPrefix returns the prefix of the variables holding the credentials of a relay target
+func CredentialsEnvPrefix(targetName string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, targetName)
+	return "PORTAL_RELAY_" + name + "_"
+}
+
+// CredentialsEnvPrefixConflicts returns the indexes of the Env-mounted relay targets whose variable prefix
+// overlaps the prefix of an earlier Env-mounted target, such as "relay-a" and "relay.a",
+// or "relay" and "relay-a" whose variables the tunnel cannot tell apart
+func CredentialsEnvPrefixConflicts(targets []portalv1alpha1.RelayTarget) []int {
+	var conflicts []int
+	var prefixes []string
+	for i, target := range targets {
+		if target.CredentialsSecretRef == nil || target.CredentialsSecretRef.MountAs != portalv1alpha1.RelayCredentialsMountEnv {
+			continue
+		}
+		prefix := CredentialsEnvPrefix(target.Name)
+		if slices.ContainsFunc(prefixes, func(p string) bool {
+			return strings.HasPrefix(prefix, p) || strings.HasPrefix(p, prefix)
+		}) {
+			conflicts = append(conflicts, i)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return conflicts
+}
+
+// applyCredentials mounts the relay credential Secrets into every tunnel container
+// and tells the tunnel where to find the credentials of each relay URL
+func applyCredentials(podSpec *corev1.PodSpec, relays []RelayEndpoint) {
+	for i, relay := range relays {
+		if relay.Credentials == nil {
+			continue
+		}
+
+		if relay.Credentials.MountAs == portalv1alpha1.RelayCredentialsMountEnv {
+			prefix := CredentialsEnvPrefix(relay.Name)
+			for j := range podSpec.Containers {
+				container := &podSpec.Containers[j]
+				container.EnvFrom = append(container.EnvFrom, corev1.EnvFromSource{
+					Prefix:    prefix,
+					SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: relay.Credentials.Name}},
+				})
+				container.Args = append(container.Args, "--relay-credentials-env", relay.URL+"="+prefix)
+			}
+			continue
+		}
+
+		volumeName := fmt.Sprintf("relay-credentials-%d", i)
+		mountPath := path.Join(CredentialsMountPath, relay.Name)
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: volumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  relay.Credentials.Name,
+					DefaultMode: ptr.To(secretVolumeMode),
+				},
+			},
+		})
+		for j := range podSpec.Containers {
+			container := &podSpec.Containers[j]
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name:      volumeName,
+				MountPath: mountPath,
+				ReadOnly:  true,
+			})
+			container.Args = append(container.Args, "--relay-credentials-dir", relay.URL+"="+mountPath)
+		}
+	}
+}
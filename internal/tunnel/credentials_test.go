@@ -0,0 +1,142 @@
+package tunnel
+
+import (
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+func TestCredentialsHash(t *testing.T) {
+	secret := func(name, token string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Data:       map[string][]byte{"token": []byte(token)},
+		}
+	}
+
+	if got := CredentialsHash(nil); got != "" {
+		t.Errorf("CredentialsHash(nil) = %q, want empty", got)
+	}
+
+	hash := CredentialsHash([]*corev1.Secret{secret("a", "one"), secret("b", "two")})
+	if got := CredentialsHash([]*corev1.Secret{secret("b", "two"), secret("a", "one")}); got != hash {
+		t.Errorf("CredentialsHash() depends on Secret order: %q != %q", got, hash)
+	}
+	if got := CredentialsHash([]*corev1.Secret{secret("a", "one"), secret("b", "rotated")}); got == hash {
+		t.Errorf("CredentialsHash() did not change when a Secret was rotated")
+	}
+}
+
+func TestCredentialsEnvPrefix(t *testing.T) {
+	if got := CredentialsEnvPrefix("eu-west.1"); got != "PORTAL_RELAY_EU_WEST_1_" {
+		t.Errorf("CredentialsEnvPrefix() = %q, want PORTAL_RELAY_EU_WEST_1_", got)
+	}
+}
+
+func TestCredentialsEnvPrefixConflicts(t *testing.T) {
+	target := func(name string, mountAs portalv1alpha1.RelayCredentialsMountMode) portalv1alpha1.RelayTarget {
+		return portalv1alpha1.RelayTarget{
+			Name:                 name,
+			URL:                  "wss://" + name + ".example.com",
+			CredentialsSecretRef: &portalv1alpha1.RelayCredentialsReference{Name: name, MountAs: mountAs},
+		}
+	}
+	env, file := portalv1alpha1.RelayCredentialsMountEnv, portalv1alpha1.RelayCredentialsMountFile
+
+	tests := []struct {
+		name    string
+		targets []portalv1alpha1.RelayTarget
+		want    []int
+	}{
+		{name: "Distinct prefixes", targets: []portalv1alpha1.RelayTarget{target("eu", env), target("us", env)}},
+		{name: "Same prefix", targets: []portalv1alpha1.RelayTarget{target("relay-a", env), target("relay.a", env)}, want: []int{1}},
+		{name: "Nested prefix", targets: []portalv1alpha1.RelayTarget{target("relay-a", env), target("relay", env)}, want: []int{1}},
+		{name: "File mounts", targets: []portalv1alpha1.RelayTarget{target("relay-a", env), target("relay.a", file)}},
+		{name: "No credentials", targets: []portalv1alpha1.RelayTarget{{Name: "relay-a"}, {Name: "relay.a"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CredentialsEnvPrefixConflicts(tt.targets); !slices.Equal(got, tt.want) {
+				t.Errorf("CredentialsEnvPrefixConflicts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildDeploymentCredentials(t *testing.T) {
+	portalExpose := &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: portalv1alpha1.PortalExposeSpec{
+			App: portalv1alpha1.AppSpec{
+				Name:    "test-app",
+				Service: portalv1alpha1.ServiceRef{Name: "test-svc", Port: intstr.FromInt32(80)},
+			},
+		},
+	}
+	tunnelClass := &portalv1alpha1.TunnelClass{Spec: portalv1alpha1.TunnelClassSpec{Replicas: 1, Size: "small"}}
+	relays := []RelayEndpoint{
+		{Name: "public", URL: "wss://public.example.com"},
+		{
+			Name:        "private",
+			URL:         "wss://private.example.com",
+			Credentials: &portalv1alpha1.RelayCredentialsReference{Name: "private-token", MountAs: portalv1alpha1.RelayCredentialsMountFile},
+		},
+		{
+			Name:        "env",
+			URL:         "wss://env.example.com",
+			Credentials: &portalv1alpha1.RelayCredentialsReference{Name: "env-token", MountAs: portalv1alpha1.RelayCredentialsMountEnv},
+		},
+	}
+
+	if got := CredentialsSecretNames(relays); !slices.Equal(got, []string{"private-token", "env-token"}) {
+		t.Errorf("CredentialsSecretNames() = %v, want [private-token env-token]", got)
+	}
+
+	deployment := BuildDeployment(portalExpose, tunnelClass, relays, AppEndpoints(portalExpose), DefaultSizes()["small"])
+	podSpec := deployment.Spec.Template.Spec
+
+	var secretVolume *corev1.Volume
+	for i := range podSpec.Volumes {
+		if podSpec.Volumes[i].Secret != nil {
+			secretVolume = &podSpec.Volumes[i]
+		}
+	}
+	if secretVolume == nil || secretVolume.Secret.SecretName != "private-token" {
+		t.Fatalf("Volumes = %v, want a volume for Secret private-token", podSpec.Volumes)
+	}
+
+	container := podSpec.Containers[0]
+	mounted := false
+	for _, mount := range container.VolumeMounts {
+		if mount.Name == secretVolume.Name && mount.MountPath == "/etc/portal/relays/private" && mount.ReadOnly {
+			mounted = true
+		}
+	}
+	if !mounted {
+		t.Errorf("VolumeMounts = %v, want private-token read-only at /etc/portal/relays/private", container.VolumeMounts)
+	}
+	if i := slices.Index(container.Args, "--relay-credentials-dir"); i < 0 ||
+		container.Args[i+1] != "wss://private.example.com=/etc/portal/relays/private" {
+		t.Errorf("Args = %v, want --relay-credentials-dir for the private relay", container.Args)
+	}
+
+	if len(container.EnvFrom) != 1 || container.EnvFrom[0].SecretRef.Name != "env-token" ||
+		container.EnvFrom[0].Prefix != "PORTAL_RELAY_ENV_" {
+		t.Errorf("EnvFrom = %v, want env-token with prefix PORTAL_RELAY_ENV_", container.EnvFrom)
+	}
+	if i := slices.Index(container.Args, "--relay-credentials-env"); i < 0 ||
+		container.Args[i+1] != "wss://env.example.com=PORTAL_RELAY_ENV_" {
+		t.Errorf("Args = %v, want --relay-credentials-env for the env relay", container.Args)
+	}
+
+	SetCredentialsHash(&deployment.Spec.Template, "abc")
+	if got := deployment.Spec.Template.Annotations[CredentialsHashAnnotation]; got != "abc" {
+		t.Errorf("Credentials hash annotation = %q, want abc", got)
+	}
+}
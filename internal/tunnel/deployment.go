@@ -21,7 +21,6 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -34,42 +33,47 @@ const (
 )
 
 // BuildDeployment creates a Deployment spec for tunnel pods
-func BuildDeployment(portalExpose *portalv1alpha1.PortalExpose, tunnelClass *portalv1alpha1.TunnelClass) *appsv1.Deployment {
+// relays are the resolved endpoints of portalExpose.Spec.Relay.Targets, in order
+// endpoints are AppEndpoints(portalExpose) with ServicePort resolved; each gets its own tunnel container
+// resources are the per-container resources of the TunnelClass, see SizeTable.Resources
+func BuildDeployment(
+	portalExpose *portalv1alpha1.PortalExpose,
+	tunnelClass *portalv1alpha1.TunnelClass,
+	relays []RelayEndpoint,
+	endpoints []AppEndpoint,
+	resources corev1.ResourceRequirements,
+) *appsv1.Deployment {
 	name := portalExpose.Name + "-tunnel"
 	namespace := portalExpose.Namespace
 
-	labels := map[string]string{
-		"app.kubernetes.io/name":         "portal-tunnel",
-		"app.kubernetes.io/component":    "tunnel",
-		"app.kubernetes.io/managed-by":   "portal-expose-controller",
-		"portal.gosuda.org/portalexpose": portalExpose.Name,
-	}
+	labels := PodLabels(portalExpose)
 
-	// Container args matching portal-tunnel command:
-	// bin/portal-tunnel expose --relay <url> [--relay <url> ...] --host localhost --port 8080 --name <service>
-	args := []string{
-		"expose",
-		"--name", portalExpose.Spec.App.Name,
-		"--host", fmt.Sprintf("%s.%s.svc.cluster.local", portalExpose.Spec.App.Service.Name, portalExpose.Namespace),
-		"--port", fmt.Sprintf("%d", portalExpose.Spec.App.Service.Port),
-	}
-	// Add all relay URLs
-	for _, target := range portalExpose.Spec.Relay.Targets {
-		args = append(args, "--relay", target.URL)
+	// Autoscaled Deployments start at the lower bound of the HPA
+	replicas := tunnelClass.Spec.Replicas
+	if autoscaling := tunnelClass.Spec.Autoscaling; autoscaling != nil {
+		replicas = autoscaling.MinReplicas
 	}
 
-	// Get resources for size
-	resources := GetResourcesForSize(tunnelClass.Spec.Size)
+	containers := make([]corev1.Container, 0, len(endpoints))
+	for i, endpoint := range endpoints {
+		containers = append(containers, buildTunnelContainer(portalExpose, endpoint, i, relays, resources))
+	}
+	applyProbes(containers, tunnelClass, len(relays))
 
 	// Create deployment
 	deployment := &appsv1.Deployment{
+		// The controller applies the Deployment server-side, which needs its kind
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
 			Namespace: namespace,
 			Labels:    labels,
 		},
 		Spec: appsv1.DeploymentSpec{
-			Replicas: &tunnelClass.Spec.Replicas,
+			Replicas: &replicas,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
 			},
@@ -85,62 +89,76 @@ func BuildDeployment(portalExpose *portalv1alpha1.PortalExpose, tunnelClass *por
 					Labels: labels,
 				},
 				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:      "tunnel",
-							Image:     TunnelImage,
-							Args:      args,
-							Resources: resources,
-						},
-					},
+					Containers:   containers,
 					NodeSelector: tunnelClass.Spec.NodeSelector,
 					Tolerations:  tunnelClass.Spec.Tolerations,
 				},
 			},
 		},
 	}
+	applyPodTemplate(&deployment.Spec.Template, tunnelClass.Spec.PodTemplate)
+	applySecurityContext(&deployment.Spec.Template.Spec, ActiveSecurityOverride(tunnelClass))
+	applyIdentity(&deployment.Spec.Template.Spec, portalExpose)
+	applyCredentials(&deployment.Spec.Template.Spec, relays)
 
 	return deployment
 }
 
-// GetResourcesForSize returns resource requirements for a given size
-func GetResourcesForSize(size string) corev1.ResourceRequirements {
-	switch size {
-	case "small":
-		return corev1.ResourceRequirements{
-			Requests: corev1.ResourceList{
-				corev1.ResourceCPU:    resource.MustParse("100m"),
-				corev1.ResourceMemory: resource.MustParse("128Mi"),
-			},
-			Limits: corev1.ResourceList{
-				corev1.ResourceCPU:    resource.MustParse("500m"),
-				corev1.ResourceMemory: resource.MustParse("512Mi"),
-			},
-		}
-	case "medium":
-		return corev1.ResourceRequirements{
-			Requests: corev1.ResourceList{
-				corev1.ResourceCPU:    resource.MustParse("250m"),
-				corev1.ResourceMemory: resource.MustParse("256Mi"),
-			},
-			Limits: corev1.ResourceList{
-				corev1.ResourceCPU:    resource.MustParse("1000m"),
-				corev1.ResourceMemory: resource.MustParse("1Gi"),
-			},
-		}
-	case "large":
-		return corev1.ResourceRequirements{
-			Requests: corev1.ResourceList{
-				corev1.ResourceCPU:    resource.MustParse("500m"),
-				corev1.ResourceMemory: resource.MustParse("512Mi"),
-			},
-			Limits: corev1.ResourceList{
-				corev1.ResourceCPU:    resource.MustParse("2000m"),
-				corev1.ResourceMemory: resource.MustParse("2Gi"),
+// PodLabels returns the labels selecting the tunnel pods of a PortalExpose
+func PodLabels(portalExpose *portalv1alpha1.PortalExpose) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":         "portal-tunnel",
+		"app.kubernetes.io/component":    "tunnel",
+		"app.kubernetes.io/managed-by":   "portal-expose-controller",
+		"portal.gosuda.org/portalexpose": portalExpose.Name,
+	}
+}
+
+// buildTunnelContainer creates the tunnel container publishing one endpoint
+// Containers share the pod network, so the i-th container serves its status endpoint on StatusPort+i
+func buildTunnelContainer(
+	portalExpose *portalv1alpha1.PortalExpose,
+	endpoint AppEndpoint,
+	index int,
+	relays []RelayEndpoint,
+	resources corev1.ResourceRequirements,
+) corev1.Container {
+	containerName := "tunnel"
+	if endpoint.Name != PrimaryEndpointName {
+		containerName = "tunnel-" + endpoint.Name
+	}
+	statusPortName := StatusPortName
+	if index > 0 {
+		statusPortName = fmt.Sprintf("%s-%d", StatusPortName, index)
+	}
+	statusPort := StatusPort + int32(index)
+
+	// Container args matching portal-tunnel command:
+	// bin/portal-tunnel expose --relay <url> [--relay <url> ...] --host localhost --port 8080 --name <service>
+	// --status-addr serves per-relay session state that the controller reads into status.relay
+	args := []string{
+		"expose",
+		"--name", endpoint.Subdomain,
+		"--host", fmt.Sprintf("%s.%s.svc.cluster.local", endpoint.Service.Name, portalExpose.Namespace),
+		"--port", fmt.Sprintf("%d", endpoint.ServicePort),
+		"--status-addr", fmt.Sprintf(":%d", statusPort),
+	}
+	// Add all relay URLs
+	for _, relay := range relays {
+		args = append(args, "--relay", relay.URL)
+	}
+
+	return corev1.Container{
+		Name:  containerName,
+		Image: TunnelImage,
+		Args:  args,
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          statusPortName,
+				ContainerPort: statusPort,
+				Protocol:      corev1.ProtocolTCP,
 			},
-		}
-	default:
-		// Default to small if size is unrecognized
-		return GetResourcesForSize("small")
+		},
+		Resources: resources,
 	}
 }
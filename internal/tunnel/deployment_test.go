@@ -1,10 +1,13 @@
 package tunnel
 
 import (
+	"slices"
 	"testing"
 
 	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func TestBuildDeployment(t *testing.T) {
@@ -25,7 +28,7 @@ func TestBuildDeployment(t *testing.T) {
 				Spec: portalv1alpha1.PortalExposeSpec{
 					App: portalv1alpha1.AppSpec{
 						Name:    "test-app",
-						Service: portalv1alpha1.ServiceRef{Name: "test-svc", Port: 80},
+						Service: portalv1alpha1.ServiceRef{Name: "test-svc", Port: intstr.FromInt32(80)},
 					},
 					Relay: portalv1alpha1.RelaySpec{
 						Targets: []portalv1alpha1.RelayTarget{
@@ -53,7 +56,7 @@ func TestBuildDeployment(t *testing.T) {
 				Spec: portalv1alpha1.PortalExposeSpec{
 					App: portalv1alpha1.AppSpec{
 						Name:    "test-app",
-						Service: portalv1alpha1.ServiceRef{Name: "test-svc", Port: 80},
+						Service: portalv1alpha1.ServiceRef{Name: "test-svc", Port: intstr.FromInt32(80)},
 					},
 					Relay: portalv1alpha1.RelaySpec{
 						Targets: []portalv1alpha1.RelayTarget{
@@ -65,6 +68,7 @@ func TestBuildDeployment(t *testing.T) {
 			tunnelClass: &portalv1alpha1.TunnelClass{
 				Spec: portalv1alpha1.TunnelClassSpec{
 					Replicas: 3,
+					Size:     "large",
 				},
 			},
 			expectedImage:    "ghcr.io/gosuda/portal-tunnel:1.0.0",
@@ -74,12 +78,23 @@ func TestBuildDeployment(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			deployment := BuildDeployment(tt.portalExpose, tt.tunnelClass)
+			relays := []RelayEndpoint{{Name: "relay", URL: "wss://relay.example.com", PublicDomain: "relay.example.com"}}
+			endpoints := AppEndpoints(tt.portalExpose)
+			endpoints[0].ServicePort = 80
+			resources, err := DefaultSizes().Resources(tt.tunnelClass)
+			if err != nil {
+				t.Fatalf("Resources() error = %v", err)
+			}
+			deployment := BuildDeployment(tt.portalExpose, tt.tunnelClass, relays, endpoints, resources)
 
 			if deployment.Name != tt.portalExpose.Name+"-tunnel" {
 				t.Errorf("BuildDeployment() name = %v, want %v", deployment.Name, tt.portalExpose.Name+"-tunnel")
 			}
 
+			if deployment.APIVersion != "apps/v1" || deployment.Kind != "Deployment" {
+				t.Errorf("BuildDeployment() type = %s/%s, want apps/v1/Deployment", deployment.APIVersion, deployment.Kind)
+			}
+
 			if *deployment.Spec.Replicas != tt.expectedReplicas {
 				t.Errorf("BuildDeployment() replicas = %v, want %v", *deployment.Spec.Replicas, tt.expectedReplicas)
 			}
@@ -88,6 +103,72 @@ func TestBuildDeployment(t *testing.T) {
 			if container.Image != tt.expectedImage {
 				t.Errorf("BuildDeployment() image = %v, want %v", container.Image, tt.expectedImage)
 			}
+
+			if !container.Resources.Requests.Cpu().Equal(resources.Requests[corev1.ResourceCPU]) {
+				t.Errorf("BuildDeployment() resources = %v, want %v", container.Resources, resources)
+			}
+
+			if i := slices.Index(container.Args, "--port"); i < 0 || container.Args[i+1] != "80" {
+				t.Errorf("BuildDeployment() args = %v, want --port 80", container.Args)
+			}
+
+			if !slices.Contains(container.Args, relays[0].URL) {
+				t.Errorf("BuildDeployment() args = %v, want relay URL %v", container.Args, relays[0].URL)
+			}
 		})
 	}
 }
+
+func TestBuildDeploymentMultipleEndpoints(t *testing.T) {
+	portalExpose := &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: portalv1alpha1.PortalExposeSpec{
+			App: portalv1alpha1.AppSpec{
+				Name:    "test-app",
+				Service: portalv1alpha1.ServiceRef{Name: "test-svc", Port: intstr.FromString("http")},
+			},
+			Endpoints: []portalv1alpha1.EndpointSpec{
+				{
+					Name:      "grpc",
+					Subdomain: "test-app-grpc",
+					Service:   portalv1alpha1.ServiceRef{Name: "test-grpc", Port: intstr.FromInt32(9000)},
+				},
+			},
+		},
+	}
+	tunnelClass := &portalv1alpha1.TunnelClass{Spec: portalv1alpha1.TunnelClassSpec{Replicas: 2, Size: "small"}}
+	relays := []RelayEndpoint{{Name: "relay", URL: "wss://relay.example.com", PublicDomain: "relay.example.com"}}
+
+	endpoints := AppEndpoints(portalExpose)
+	endpoints[0].ServicePort = 80
+	endpoints[1].ServicePort = 9000
+	deployment := BuildDeployment(portalExpose, tunnelClass, relays, endpoints, DefaultSizes()["small"])
+
+	containers := deployment.Spec.Template.Spec.Containers
+	if len(containers) != 2 {
+		t.Fatalf("BuildDeployment() containers = %d, want 2", len(containers))
+	}
+
+	expected := []struct {
+		name, subdomain, host, port, statusAddr string
+	}{
+		{"tunnel", "test-app", "test-svc.default.svc.cluster.local", "80", ":8090"},
+		{"tunnel-grpc", "test-app-grpc", "test-grpc.default.svc.cluster.local", "9000", ":8091"},
+	}
+	for i, want := range expected {
+		container := containers[i]
+		if container.Name != want.name {
+			t.Errorf("container[%d] name = %v, want %v", i, container.Name, want.name)
+		}
+		for flag, value := range map[string]string{
+			"--name": want.subdomain, "--host": want.host, "--port": want.port, "--status-addr": want.statusAddr,
+		} {
+			if j := slices.Index(container.Args, flag); j < 0 || container.Args[j+1] != value {
+				t.Errorf("container[%d] args = %v, want %s %s", i, container.Args, flag, value)
+			}
+		}
+		if !slices.Contains(container.Args, relays[0].URL) {
+			t.Errorf("container[%d] args = %v, want relay URL %v", i, container.Args, relays[0].URL)
+		}
+	}
+}
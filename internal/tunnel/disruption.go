@@ -0,0 +1,59 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+// BuildPodDisruptionBudget creates the PodDisruptionBudget guarding the tunnel pods of BuildDeployment
+// The policy comes from the TunnelClass and defaults to maxUnavailable 1
+func BuildPodDisruptionBudget(
+	portalExpose *portalv1alpha1.PortalExpose,
+	tunnelClass *portalv1alpha1.TunnelClass,
+) *policyv1.PodDisruptionBudget {
+	labels := PodLabels(portalExpose)
+
+	spec := policyv1.PodDisruptionBudgetSpec{
+		Selector: &metav1.LabelSelector{
+			MatchLabels: labels,
+		},
+		// Tunnels are unready while their relays are down; such pods must not block node drains
+		UnhealthyPodEvictionPolicy: ptr.To(policyv1.AlwaysAllow),
+	}
+	if budget := tunnelClass.Spec.DisruptionBudget; budget != nil && budget.MinAvailable != nil {
+		spec.MinAvailable = budget.MinAvailable
+	} else if budget != nil && budget.MaxUnavailable != nil {
+		spec.MaxUnavailable = budget.MaxUnavailable
+	} else {
+		spec.MaxUnavailable = ptr.To(intstr.FromInt32(1))
+	}
+
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      portalExpose.Name + "-tunnel",
+			Namespace: portalExpose.Namespace,
+			Labels:    labels,
+		},
+		Spec: spec,
+	}
+}
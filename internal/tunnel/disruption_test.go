@@ -0,0 +1,71 @@
+package tunnel
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+func TestBuildPodDisruptionBudget(t *testing.T) {
+	portalExpose := &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+	}
+
+	tests := []struct {
+		name               string
+		budget             *portalv1alpha1.TunnelDisruptionBudget
+		wantMinAvailable   *intstr.IntOrString
+		wantMaxUnavailable *intstr.IntOrString
+	}{
+		{
+			name:               "Default",
+			wantMaxUnavailable: ptr.To(intstr.FromInt32(1)),
+		},
+		{
+			name:             "Min available",
+			budget:           &portalv1alpha1.TunnelDisruptionBudget{MinAvailable: ptr.To(intstr.FromString("50%"))},
+			wantMinAvailable: ptr.To(intstr.FromString("50%")),
+		},
+		{
+			name:               "Max unavailable",
+			budget:             &portalv1alpha1.TunnelDisruptionBudget{MaxUnavailable: ptr.To(intstr.FromInt32(2))},
+			wantMaxUnavailable: ptr.To(intstr.FromInt32(2)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				Spec: portalv1alpha1.TunnelClassSpec{Replicas: 3, Size: "small", DisruptionBudget: tt.budget},
+			}
+			pdb := BuildPodDisruptionBudget(portalExpose, tunnelClass)
+
+			if pdb.Name != "test-app-tunnel" || pdb.Namespace != "default" {
+				t.Errorf("PodDisruptionBudget = %s/%s, want default/test-app-tunnel", pdb.Namespace, pdb.Name)
+			}
+			deployment := BuildDeployment(portalExpose, tunnelClass, nil, nil, DefaultSizes()["small"])
+			for key, value := range deployment.Spec.Selector.MatchLabels {
+				if pdb.Spec.Selector.MatchLabels[key] != value {
+					t.Errorf("Selector label %s = %q, want %q", key, pdb.Spec.Selector.MatchLabels[key], value)
+				}
+			}
+			if !equalIntOrString(pdb.Spec.MinAvailable, tt.wantMinAvailable) {
+				t.Errorf("MinAvailable = %v, want %v", pdb.Spec.MinAvailable, tt.wantMinAvailable)
+			}
+			if !equalIntOrString(pdb.Spec.MaxUnavailable, tt.wantMaxUnavailable) {
+				t.Errorf("MaxUnavailable = %v, want %v", pdb.Spec.MaxUnavailable, tt.wantMaxUnavailable)
+			}
+		})
+	}
+}
+
+func equalIntOrString(a, b *intstr.IntOrString) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+// RelayEndpoint is a relay target resolved to a concrete URL and public domain,
+// either from an inline URL or from the referenced ClusterRelay
+type RelayEndpoint struct {
+	// Name matches spec.relay.targets[].name
+	Name string
+
+	// URL is the WebSocket relay URL passed to the tunnel
+	URL string
+
+	// PublicDomain is the domain applications are published under on this relay
+	PublicDomain string
+
+	// ClusterRelay is the referenced ClusterRelay name, empty for inline URLs
+	ClusterRelay string
+
+	// Credentials references the Secret with the relay credentials, nil for public relays
+	Credentials *portalv1alpha1.RelayCredentialsReference
+}
+
+// PublicURL returns the public URL of the given app on this relay
+func (e RelayEndpoint) PublicURL(appName string) string {
+	return "https://" + appName + "." + e.PublicDomain
+}
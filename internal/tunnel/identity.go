@@ -0,0 +1,160 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+const (
+	// RotateIdentityAnnotation on a PortalExpose requests a new tunnel identity whenever its value changes
+	RotateIdentityAnnotation = "portal.gosuda.org/rotate-identity"
+
+	// IdentityRotationAnnotation records on the identity Secret the RotateIdentityAnnotation value it was generated for
+	IdentityRotationAnnotation = "portal.gosuda.org/identity-rotation"
+
+	// IdentityFingerprintAnnotation records the identity fingerprint on the pod template,
+	// so a rotated identity rolls the tunnel pods
+	IdentityFingerprintAnnotation = "portal.gosuda.org/identity-fingerprint"
+
+	// IdentityMountPath is the directory the identity Secret is mounted at
+	IdentityMountPath = "/etc/portal/identity"
+
+	// IdentityPrivateKey is the Secret key of the PKCS #8 PEM private key
+	IdentityPrivateKey = "identity.key"
+
+	// IdentityPublicKey is the Secret key of the PKIX PEM public key
+	IdentityPublicKey = "identity.pub"
+
+	identityVolumeName = "identity"
+)
+
+// IdentitySecretName returns the name of the Secret holding the tunnel identity of a PortalExpose
+func IdentitySecretName(portalExpose *portalv1alpha1.PortalExpose) string {
+	return portalExpose.Name + "-tunnel-identity"
+}
+
+// BuildIdentitySecret generates a new Ed25519 tunnel identity for the PortalExpose
+// The Secret records the current RotateIdentityAnnotation value, see NeedsIdentityRotation
+func BuildIdentitySecret(portalExpose *portalv1alpha1.PortalExpose) (*corev1.Secret, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := PodLabels(portalExpose)
+	labels[SecretLabel] = "true"
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      IdentitySecretName(portalExpose),
+			Namespace: portalExpose.Namespace,
+			Labels:    labels,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			IdentityPrivateKey: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}),
+			IdentityPublicKey:  pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}),
+		},
+	}
+	if rotation, ok := portalExpose.Annotations[RotateIdentityAnnotation]; ok {
+		secret.Annotations = map[string]string{IdentityRotationAnnotation: rotation}
+	}
+	return secret, nil
+}
+
+// NeedsIdentityRotation reports whether the identity Secret must be regenerated:
+// the PortalExpose requests a rotation the Secret was not generated for, or the Secret holds no valid key
+// Removing the RotateIdentityAnnotation keeps the current identity
+func NeedsIdentityRotation(portalExpose *portalv1alpha1.PortalExpose, secret *corev1.Secret) bool {
+	if rotation, ok := portalExpose.Annotations[RotateIdentityAnnotation]; ok && rotation != secret.Annotations[IdentityRotationAnnotation] {
+		return true
+	}
+	_, err := IdentityFingerprint(secret)
+	return err != nil
+}
+
+// IdentityFingerprint returns the fingerprint of the identity public key, in the OpenSSH "SHA256:<base64>" format
+func IdentityFingerprint(secret *corev1.Secret) (string, error) {
+	block, _ := pem.Decode(secret.Data[IdentityPublicKey])
+	if block == nil {
+		return "", errors.New("identity Secret holds no PEM public key")
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	ed25519Key, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return "", errors.New("identity public key is not an Ed25519 key")
+	}
+	sum := sha256.Sum256(ed25519Key)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:]), nil
+}
+
+// SetIdentityFingerprint stamps the identity fingerprint on the pod template
+func SetIdentityFingerprint(template *corev1.PodTemplateSpec, fingerprint string) {
+	if fingerprint == "" {
+		return
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[IdentityFingerprintAnnotation] = fingerprint
+}
+
+// applyIdentity mounts the identity Secret into every tunnel container
+func applyIdentity(podSpec *corev1.PodSpec, portalExpose *portalv1alpha1.PortalExpose) {
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: identityVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName:  IdentitySecretName(portalExpose),
+				DefaultMode: ptr.To(secretVolumeMode),
+			},
+		},
+	})
+	for i := range podSpec.Containers {
+		container := &podSpec.Containers[i]
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      identityVolumeName,
+			MountPath: IdentityMountPath,
+			ReadOnly:  true,
+		})
+		container.Args = append(container.Args, "--identity-key", path.Join(IdentityMountPath, IdentityPrivateKey))
+	}
+}
@@ -0,0 +1,105 @@
+package tunnel
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+func TestBuildIdentitySecret(t *testing.T) {
+	portalExpose := &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+	}
+
+	first, err := BuildIdentitySecret(portalExpose)
+	if err != nil {
+		t.Fatalf("BuildIdentitySecret() error = %v", err)
+	}
+	if first.Name != "test-app-tunnel-identity" || first.Namespace != "default" {
+		t.Errorf("Secret = %s/%s, want default/test-app-tunnel-identity", first.Namespace, first.Name)
+	}
+	if !strings.Contains(string(first.Data[IdentityPrivateKey]), "PRIVATE KEY") {
+		t.Errorf("Private key = %q, want a PEM private key", first.Data[IdentityPrivateKey])
+	}
+
+	fingerprint, err := IdentityFingerprint(first)
+	if err != nil || !strings.HasPrefix(fingerprint, "SHA256:") {
+		t.Fatalf("IdentityFingerprint() = %q, %v, want a SHA256 fingerprint", fingerprint, err)
+	}
+
+	second, err := BuildIdentitySecret(portalExpose)
+	if err != nil {
+		t.Fatalf("BuildIdentitySecret() error = %v", err)
+	}
+	if other, _ := IdentityFingerprint(second); other == fingerprint {
+		t.Errorf("Two generated identities share fingerprint %s", fingerprint)
+	}
+}
+
+func TestNeedsIdentityRotation(t *testing.T) {
+	valid, err := BuildIdentitySecret(&portalv1alpha1.PortalExpose{ObjectMeta: metav1.ObjectMeta{Name: "test-app"}})
+	if err != nil {
+		t.Fatalf("BuildIdentitySecret() error = %v", err)
+	}
+	rotated := valid.DeepCopy()
+	rotated.Annotations = map[string]string{IdentityRotationAnnotation: "2025-01-01"}
+
+	tests := []struct {
+		name     string
+		request  map[string]string
+		secret   *corev1.Secret
+		expected bool
+	}{
+		{name: "No rotation requested", secret: valid},
+		{name: "Rotation requested", request: map[string]string{RotateIdentityAnnotation: "2025-01-01"}, secret: valid, expected: true},
+		{name: "Rotation already done", request: map[string]string{RotateIdentityAnnotation: "2025-01-01"}, secret: rotated},
+		{name: "Request removed after rotation", secret: rotated},
+		{name: "Secret without a key", secret: &corev1.Secret{}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			portalExpose := &portalv1alpha1.PortalExpose{ObjectMeta: metav1.ObjectMeta{Annotations: tt.request}}
+			if got := NeedsIdentityRotation(portalExpose, tt.secret); got != tt.expected {
+				t.Errorf("NeedsIdentityRotation() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildDeploymentIdentity(t *testing.T) {
+	portalExpose := &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: portalv1alpha1.PortalExposeSpec{
+			App: portalv1alpha1.AppSpec{
+				Name:    "test-app",
+				Service: portalv1alpha1.ServiceRef{Name: "test-svc", Port: intstr.FromInt32(80)},
+			},
+			Endpoints: []portalv1alpha1.EndpointSpec{
+				{Name: "grpc", Subdomain: "test-app-grpc", Service: portalv1alpha1.ServiceRef{Name: "test-grpc", Port: intstr.FromInt32(9000)}},
+			},
+		},
+	}
+	tunnelClass := &portalv1alpha1.TunnelClass{Spec: portalv1alpha1.TunnelClassSpec{Replicas: 2, Size: "small"}}
+
+	deployment := BuildDeployment(portalExpose, tunnelClass, nil, AppEndpoints(portalExpose), DefaultSizes()["small"])
+	volumes := deployment.Spec.Template.Spec.Volumes
+	if !slices.ContainsFunc(volumes, func(v corev1.Volume) bool {
+		return v.Secret != nil && v.Secret.SecretName == "test-app-tunnel-identity"
+	}) {
+		t.Errorf("Volumes = %v, want the identity Secret", volumes)
+	}
+
+	// Every container publishes under the same identity
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if i := slices.Index(container.Args, "--identity-key"); i < 0 || container.Args[i+1] != "/etc/portal/identity/identity.key" {
+			t.Errorf("Container %s args = %v, want --identity-key", container.Name, container.Args)
+		}
+	}
+}
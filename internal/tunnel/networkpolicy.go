@@ -0,0 +1,179 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+// ControllerPodLabels select the controller pods, which read the status endpoint of tunnel pods
+// The controller manifests set them on the controller pod template
+var ControllerPodLabels = map[string]string{
+	"portal.gosuda.org/controller": "true",
+}
+
+// anyAddress returns peers matching every IPv4 and IPv6 destination
+func anyAddress() []networkingv1.NetworkPolicyPeer {
+	return []networkingv1.NetworkPolicyPeer{
+		{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0"}},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "::/0"}},
+	}
+}
+
+// ValidateNetworkPolicy checks the relay address ranges of a TunnelClass network policy
+func ValidateNetworkPolicy(np *portalv1alpha1.TunnelNetworkPolicy, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if np == nil {
+		return allErrs
+	}
+	for i, cidr := range np.RelayCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("relayCIDRs").Index(i), cidr, "must be a CIDR such as 203.0.113.0/24"))
+		}
+	}
+	return allErrs
+}
+
+// BuildNetworkPolicy creates the NetworkPolicy isolating the tunnel pods of BuildDeployment
+// It returns nil when the TunnelClass does not enable network policies
+// endpoints must have their Service ports, selectors and target ports resolved
+func BuildNetworkPolicy(
+	portalExpose *portalv1alpha1.PortalExpose,
+	tunnelClass *portalv1alpha1.TunnelClass,
+	relays []RelayEndpoint,
+	endpoints []AppEndpoint,
+) (*networkingv1.NetworkPolicy, error) {
+	np := tunnelClass.Spec.NetworkPolicy
+	if np == nil || !np.Enabled {
+		return nil, nil
+	}
+	labels := PodLabels(portalExpose)
+
+	// DNS, wherever the cluster resolver runs (CoreDNS, NodeLocal DNSCache, ...)
+	egress := []networkingv1.NetworkPolicyEgressRule{{
+		Ports: []networkingv1.NetworkPolicyPort{
+			{Protocol: ptr.To(corev1.ProtocolUDP), Port: ptr.To(intstr.FromInt32(53))},
+			{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(53))},
+		},
+	}}
+
+	// Relays, on their ports only
+	relayPeers := anyAddress()
+	if len(np.RelayCIDRs) > 0 {
+		relayPeers = nil
+		for _, cidr := range np.RelayCIDRs {
+			relayPeers = append(relayPeers, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+		}
+	}
+	var relayPorts []networkingv1.NetworkPolicyPort
+	seenPorts := make(map[int32]bool)
+	for _, relay := range relays {
+		port, err := relayPort(relay.URL)
+		if err != nil {
+			return nil, err
+		}
+		if !seenPorts[port] {
+			seenPorts[port] = true
+			relayPorts = append(relayPorts, networkingv1.NetworkPolicyPort{
+				Protocol: ptr.To(corev1.ProtocolTCP),
+				Port:     ptr.To(intstr.FromInt32(port)),
+			})
+		}
+	}
+	egress = append(egress, networkingv1.NetworkPolicyEgressRule{To: relayPeers, Ports: relayPorts})
+
+	// Backend pods, on the port their Service forwards to
+	// Services without a selector route to manually managed endpoints, which pods cannot select
+	for _, endpoint := range endpoints {
+		peers := anyAddress()
+		if len(endpoint.ServiceSelector) > 0 {
+			peers = []networkingv1.NetworkPolicyPeer{{
+				PodSelector: &metav1.LabelSelector{MatchLabels: endpoint.ServiceSelector},
+			}}
+		}
+		egress = append(egress, networkingv1.NetworkPolicyEgressRule{
+			To: peers,
+			Ports: []networkingv1.NetworkPolicyPort{{
+				Protocol: ptr.To(corev1.ProtocolTCP),
+				Port:     ptr.To(endpoint.TargetPort),
+			}},
+		})
+	}
+
+	// No ingress but the controller reading the status endpoints
+	var statusPorts []networkingv1.NetworkPolicyPort
+	for i := range endpoints {
+		statusPorts = append(statusPorts, networkingv1.NetworkPolicyPort{
+			Protocol: ptr.To(corev1.ProtocolTCP),
+			Port:     ptr.To(intstr.FromInt32(StatusPort + int32(i))),
+		})
+	}
+	ingress := []networkingv1.NetworkPolicyIngressRule{{
+		From: []networkingv1.NetworkPolicyPeer{{
+			NamespaceSelector: &metav1.LabelSelector{},
+			PodSelector:       &metav1.LabelSelector{MatchLabels: ControllerPodLabels},
+		}},
+		Ports: statusPorts,
+	}}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      portalExpose.Name + "-tunnel",
+			Namespace: portalExpose.Namespace,
+			Labels:    labels,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: labels},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Ingress:     ingress,
+			Egress:      egress,
+		},
+	}, nil
+}
+
+// relayPort returns the TCP port of a relay URL, defaulting by scheme
+func relayPort(relayURL string) (int32, error) {
+	u, err := url.Parse(relayURL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid relay URL %q: %w", relayURL, err)
+	}
+	if portStr := u.Port(); portStr != "" {
+		port, err := strconv.ParseInt(portStr, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid port in relay URL %q: %w", relayURL, err)
+		}
+		return int32(port), nil
+	}
+	switch u.Scheme {
+	case "ws", "http":
+		return 80, nil
+	default:
+		return 443, nil
+	}
+}
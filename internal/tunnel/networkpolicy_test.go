@@ -0,0 +1,138 @@
+package tunnel
+
+import (
+	"slices"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+func TestBuildNetworkPolicy(t *testing.T) {
+	portalExpose := &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+	}
+	endpoints := []AppEndpoint{
+		{
+			Name:            PrimaryEndpointName,
+			ServicePort:     80,
+			ServiceSelector: map[string]string{"app": "web"},
+			TargetPort:      intstr.FromInt32(8080),
+		},
+		{
+			Name:        "external",
+			ServicePort: 5432,
+			TargetPort:  intstr.FromInt32(5432),
+		},
+	}
+	relays := []RelayEndpoint{
+		{Name: "a", URL: "wss://a.example.com/relay"},
+		{Name: "b", URL: "wss://b.example.com:8443/relay"},
+		{Name: "c", URL: "wss://c.example.com/relay"},
+	}
+
+	tests := []struct {
+		name           string
+		networkPolicy  *portalv1alpha1.TunnelNetworkPolicy
+		wantNil        bool
+		wantRelayPeers []string
+	}{
+		{name: "Not configured", wantNil: true},
+		{name: "Disabled", networkPolicy: &portalv1alpha1.TunnelNetworkPolicy{}, wantNil: true},
+		{
+			name:           "Enabled",
+			networkPolicy:  &portalv1alpha1.TunnelNetworkPolicy{Enabled: true},
+			wantRelayPeers: []string{"0.0.0.0/0", "::/0"},
+		},
+		{
+			name:           "Relay CIDRs",
+			networkPolicy:  &portalv1alpha1.TunnelNetworkPolicy{Enabled: true, RelayCIDRs: []string{"203.0.113.0/24"}},
+			wantRelayPeers: []string{"203.0.113.0/24"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				Spec: portalv1alpha1.TunnelClassSpec{Replicas: 2, Size: "small", NetworkPolicy: tt.networkPolicy},
+			}
+			np, err := BuildNetworkPolicy(portalExpose, tunnelClass, relays, endpoints)
+			if err != nil {
+				t.Fatalf("BuildNetworkPolicy() error = %v", err)
+			}
+			if tt.wantNil {
+				if np != nil {
+					t.Errorf("BuildNetworkPolicy() = %v, want nil", np)
+				}
+				return
+			}
+
+			if len(np.Spec.PolicyTypes) != 2 {
+				t.Errorf("PolicyTypes = %v, want Ingress and Egress", np.Spec.PolicyTypes)
+			}
+			if np.Spec.PodSelector.MatchLabels["portal.gosuda.org/portalexpose"] != "test-app" {
+				t.Errorf("PodSelector = %v, want the tunnel pods", np.Spec.PodSelector.MatchLabels)
+			}
+
+			// DNS, relays, then one rule per endpoint
+			if len(np.Spec.Egress) != 4 {
+				t.Fatalf("Egress rules = %d, want 4", len(np.Spec.Egress))
+			}
+			relayRule := np.Spec.Egress[1]
+			var relayPeers []string
+			for _, peer := range relayRule.To {
+				relayPeers = append(relayPeers, peer.IPBlock.CIDR)
+			}
+			if !slices.Equal(relayPeers, tt.wantRelayPeers) {
+				t.Errorf("Relay peers = %v, want %v", relayPeers, tt.wantRelayPeers)
+			}
+			if len(relayRule.Ports) != 2 || relayRule.Ports[0].Port.IntVal != 443 || relayRule.Ports[1].Port.IntVal != 8443 {
+				t.Errorf("Relay ports = %v, want 443 and 8443", relayRule.Ports)
+			}
+
+			backendRule := np.Spec.Egress[2]
+			if len(backendRule.To) != 1 || backendRule.To[0].PodSelector.MatchLabels["app"] != "web" {
+				t.Errorf("Backend peers = %v, want the Service selector", backendRule.To)
+			}
+			if backendRule.Ports[0].Port.IntVal != 8080 {
+				t.Errorf("Backend port = %v, want target port 8080", backendRule.Ports[0].Port)
+			}
+			if selectorless := np.Spec.Egress[3]; len(selectorless.To) != 2 || selectorless.To[0].IPBlock == nil {
+				t.Errorf("Selectorless backend peers = %v, want any address", selectorless.To)
+			}
+
+			if len(np.Spec.Ingress) != 1 || len(np.Spec.Ingress[0].Ports) != 2 {
+				t.Fatalf("Ingress = %v, want one rule for both status ports", np.Spec.Ingress)
+			}
+			if from := np.Spec.Ingress[0].From; len(from) != 1 ||
+				from[0].PodSelector.MatchLabels["portal.gosuda.org/controller"] != "true" {
+				t.Errorf("Ingress from = %v, want the controller pods", from)
+			}
+		})
+	}
+}
+
+func TestRelayPort(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected int32
+	}{
+		{url: "wss://relay.example.com/relay", expected: 443},
+		{url: "ws://relay.example.com/relay", expected: 80},
+		{url: "wss://relay.example.com:8443/relay", expected: 8443},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			port, err := relayPort(tt.url)
+			if err != nil {
+				t.Fatalf("relayPort() error = %v", err)
+			}
+			if port != tt.expected {
+				t.Errorf("relayPort() = %v, want %v", port, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+const (
+	// ReservedKeyPrefix marks pod labels and annotations owned by the controller
+	ReservedKeyPrefix = "portal.gosuda.org/"
+)
+
+// reservedLabels are the selector labels BuildDeployment puts on every tunnel pod
+var reservedLabels = []string{
+	"app.kubernetes.io/name",
+	"app.kubernetes.io/component",
+	"app.kubernetes.io/managed-by",
+}
+
+// isReservedLabel reports whether a pod label key is owned by the controller
+func isReservedLabel(key string) bool {
+	for _, reserved := range reservedLabels {
+		if key == reserved {
+			return true
+		}
+	}
+	return strings.HasPrefix(key, ReservedKeyPrefix)
+}
+
+// ValidatePodTemplate checks a TunnelClass pod template overlay against the controller-owned keys
+func ValidatePodTemplate(podTemplate *portalv1alpha1.TunnelPodTemplate, path *field.Path) field.ErrorList {
+	if podTemplate == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	labelsPath := path.Child("metadata", "labels")
+	allErrs = append(allErrs, metav1validation.ValidateLabels(podTemplate.Metadata.Labels, labelsPath)...)
+	for key := range podTemplate.Metadata.Labels {
+		if isReservedLabel(key) {
+			allErrs = append(allErrs, field.Forbidden(labelsPath.Key(key), "label is owned by the controller"))
+		}
+	}
+
+	annotationsPath := path.Child("metadata", "annotations")
+	for key := range podTemplate.Metadata.Annotations {
+		if strings.HasPrefix(key, ReservedKeyPrefix) {
+			allErrs = append(allErrs, field.Forbidden(annotationsPath.Key(key), "annotation is owned by the controller"))
+		}
+	}
+	return allErrs
+}
+
+// applyPodTemplate merges a TunnelClass pod template overlay into the generated pod template
+// Controller-owned labels and annotations win, so an overlay admitted without the webhook cannot break the selector
+func applyPodTemplate(template *corev1.PodTemplateSpec, podTemplate *portalv1alpha1.TunnelPodTemplate) {
+	if podTemplate == nil {
+		return
+	}
+
+	labels := make(map[string]string, len(template.Labels)+len(podTemplate.Metadata.Labels))
+	for key, value := range podTemplate.Metadata.Labels {
+		if !isReservedLabel(key) {
+			labels[key] = value
+		}
+	}
+	for key, value := range template.Labels {
+		labels[key] = value
+	}
+	template.Labels = labels
+
+	for key, value := range podTemplate.Metadata.Annotations {
+		if strings.HasPrefix(key, ReservedKeyPrefix) {
+			continue
+		}
+		if _, ok := template.Annotations[key]; ok {
+			continue
+		}
+		if template.Annotations == nil {
+			template.Annotations = map[string]string{}
+		}
+		template.Annotations[key] = value
+	}
+
+	spec := podTemplate.Spec
+	template.Spec.Affinity = spec.Affinity
+	template.Spec.TopologySpreadConstraints = spec.TopologySpreadConstraints
+	template.Spec.PriorityClassName = spec.PriorityClassName
+	template.Spec.ImagePullSecrets = spec.ImagePullSecrets
+	template.Spec.ServiceAccountName = spec.ServiceAccountName
+}
@@ -0,0 +1,139 @@
+package tunnel
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+func TestValidatePodTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		wantErrs    int
+	}{
+		{
+			name:        "Custom labels and annotations",
+			labels:      map[string]string{"team": "platform"},
+			annotations: map[string]string{"prometheus.io/scrape": "true"},
+		},
+		{
+			name:     "Selector label",
+			labels:   map[string]string{"app.kubernetes.io/managed-by": "helm"},
+			wantErrs: 1,
+		},
+		{
+			name:     "Reserved label prefix",
+			labels:   map[string]string{"portal.gosuda.org/portalexpose": "other"},
+			wantErrs: 1,
+		},
+		{
+			name:     "Invalid label value",
+			labels:   map[string]string{"team": "not a value"},
+			wantErrs: 1,
+		},
+		{
+			name:        "Reserved annotation prefix",
+			annotations: map[string]string{"portal.gosuda.org/config-hash": "abc"},
+			wantErrs:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			podTemplate := &portalv1alpha1.TunnelPodTemplate{
+				Metadata: portalv1alpha1.TunnelPodMetadata{Labels: tt.labels, Annotations: tt.annotations},
+			}
+			errs := ValidatePodTemplate(podTemplate, field.NewPath("spec", "podTemplate"))
+			if len(errs) != tt.wantErrs {
+				t.Errorf("ValidatePodTemplate() = %v, want %d errors", errs, tt.wantErrs)
+			}
+		})
+	}
+
+	if errs := ValidatePodTemplate(nil, field.NewPath("spec", "podTemplate")); len(errs) != 0 {
+		t.Errorf("ValidatePodTemplate(nil) = %v, want no errors", errs)
+	}
+}
+
+func TestBuildDeploymentPodTemplate(t *testing.T) {
+	portalExpose := &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: portalv1alpha1.PortalExposeSpec{
+			App: portalv1alpha1.AppSpec{
+				Name:    "test-app",
+				Service: portalv1alpha1.ServiceRef{Name: "test-svc", Port: intstr.FromInt32(80)},
+			},
+		},
+	}
+	tunnelClass := &portalv1alpha1.TunnelClass{
+		Spec: portalv1alpha1.TunnelClassSpec{
+			Replicas: 2,
+			Size:     "small",
+			PodTemplate: &portalv1alpha1.TunnelPodTemplate{
+				Metadata: portalv1alpha1.TunnelPodMetadata{
+					Labels: map[string]string{
+						"team":                   "platform",
+						"app.kubernetes.io/name": "hijacked", // Only reachable with the webhook disabled
+					},
+					Annotations: map[string]string{"prometheus.io/scrape": "true"},
+				},
+				Spec: portalv1alpha1.TunnelPodSpec{
+					Affinity: &corev1.Affinity{
+						NodeAffinity: &corev1.NodeAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+								NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+									MatchExpressions: []corev1.NodeSelectorRequirement{{
+										Key: "kubernetes.io/arch", Operator: corev1.NodeSelectorOpIn, Values: []string{"arm64"},
+									}},
+								}},
+							},
+						},
+					},
+					TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{
+						MaxSkew:           1,
+						TopologyKey:       "topology.kubernetes.io/zone",
+						WhenUnsatisfiable: corev1.ScheduleAnyway,
+						MatchLabelKeys:    []string{"portal.gosuda.org/portalexpose"},
+					}},
+					PriorityClassName:  "system-cluster-critical",
+					ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "mirror"}},
+					ServiceAccountName: "tunnel",
+				},
+			},
+		},
+	}
+
+	deployment := BuildDeployment(portalExpose, tunnelClass, nil, AppEndpoints(portalExpose), DefaultSizes()["small"])
+	template := deployment.Spec.Template
+
+	if template.Labels["team"] != "platform" {
+		t.Errorf("Pod labels = %v, want team=platform", template.Labels)
+	}
+	if template.Labels["app.kubernetes.io/name"] != "portal-tunnel" ||
+		deployment.Spec.Selector.MatchLabels["app.kubernetes.io/name"] != "portal-tunnel" {
+		t.Errorf("Pod labels = %v, want the controller selector labels to win", template.Labels)
+	}
+	if _, ok := deployment.Spec.Selector.MatchLabels["team"]; ok {
+		t.Error("Selector must not include overlay labels")
+	}
+	if template.Annotations["prometheus.io/scrape"] != "true" {
+		t.Errorf("Pod annotations = %v, want prometheus.io/scrape", template.Annotations)
+	}
+	if template.Spec.Affinity == nil || len(template.Spec.TopologySpreadConstraints) != 1 {
+		t.Errorf("Pod scheduling = %+v, want affinity and topology spread", template.Spec)
+	}
+	if template.Spec.PriorityClassName != "system-cluster-critical" || template.Spec.ServiceAccountName != "tunnel" ||
+		len(template.Spec.ImagePullSecrets) != 1 {
+		t.Errorf("Pod spec = %+v, want priority class, service account and pull secret", template.Spec)
+	}
+	if len(template.Spec.Containers) != 1 || template.Spec.Containers[0].Image != TunnelImage {
+		t.Errorf("Containers = %+v, want the controller tunnel container", template.Spec.Containers)
+	}
+}
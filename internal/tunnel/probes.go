@@ -0,0 +1,100 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+const (
+	// LivenessPath reports whether the tunnel process is running, regardless of relay sessions
+	LivenessPath = "/healthz"
+
+	// ReadinessPath reports whether the tunnel holds at least --min-ready-relays relay sessions
+	ReadinessPath = "/readyz"
+)
+
+// MinConnectedRelays returns the number of relays a tunnel container needs before it is ready,
+// capped at the number of relays so a class with a high minimum still works with fewer targets
+func MinConnectedRelays(tunnelClass *portalv1alpha1.TunnelClass, relayCount int) int32 {
+	minRelays := int32(1)
+	if probes := tunnelClass.Spec.Probes; probes != nil && probes.MinConnectedRelays != nil {
+		minRelays = *probes.MinConnectedRelays
+	}
+	if int(minRelays) > relayCount && relayCount > 0 {
+		minRelays = int32(relayCount)
+	}
+	return minRelays
+}
+
+// applyProbes adds the health probes to every tunnel container
+// Readiness follows relay connectivity, so Deployment ReadyReplicas reflects tunnel health;
+// liveness only follows the process, so a relay outage does not restart the pods
+func applyProbes(containers []corev1.Container, tunnelClass *portalv1alpha1.TunnelClass, relayCount int) {
+	var readiness, liveness *portalv1alpha1.ProbeThresholds
+	if probes := tunnelClass.Spec.Probes; probes != nil {
+		readiness, liveness = probes.Readiness, probes.Liveness
+	}
+	minRelays := MinConnectedRelays(tunnelClass, relayCount)
+
+	for i := range containers {
+		container := &containers[i]
+		container.Args = append(container.Args, "--min-ready-relays", fmt.Sprintf("%d", minRelays))
+
+		port := intstr.FromString(container.Ports[0].Name)
+		container.ReadinessProbe = buildProbe(ReadinessPath, port, readiness, corev1.Probe{
+			InitialDelaySeconds: 2,
+			PeriodSeconds:       5,
+			TimeoutSeconds:      2,
+			FailureThreshold:    3,
+		})
+		container.LivenessProbe = buildProbe(LivenessPath, port, liveness, corev1.Probe{
+			InitialDelaySeconds: 10,
+			PeriodSeconds:       20,
+			TimeoutSeconds:      3,
+			FailureThreshold:    3,
+		})
+	}
+}
+
+// buildProbe returns an HTTP probe with the defaults overridden by the TunnelClass thresholds
+// Every field the API server would default is set, so comparing Deployments does not loop
+func buildProbe(path string, port intstr.IntOrString, thresholds *portalv1alpha1.ProbeThresholds, defaults corev1.Probe) *corev1.Probe {
+	if thresholds == nil {
+		thresholds = &portalv1alpha1.ProbeThresholds{}
+	}
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   path,
+				Port:   port,
+				Scheme: corev1.URISchemeHTTP,
+			},
+		},
+		InitialDelaySeconds: ptr.Deref(thresholds.InitialDelaySeconds, defaults.InitialDelaySeconds),
+		PeriodSeconds:       ptr.Deref(thresholds.PeriodSeconds, defaults.PeriodSeconds),
+		TimeoutSeconds:      ptr.Deref(thresholds.TimeoutSeconds, defaults.TimeoutSeconds),
+		FailureThreshold:    ptr.Deref(thresholds.FailureThreshold, defaults.FailureThreshold),
+		SuccessThreshold:    1,
+	}
+}
@@ -0,0 +1,86 @@
+package tunnel
+
+import (
+	"slices"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+func TestMinConnectedRelays(t *testing.T) {
+	tests := []struct {
+		name       string
+		probes     *portalv1alpha1.TunnelProbes
+		relayCount int
+		want       int32
+	}{
+		{name: "Default", relayCount: 3, want: 1},
+		{name: "Per class", probes: &portalv1alpha1.TunnelProbes{MinConnectedRelays: ptr.To(int32(2))}, relayCount: 3, want: 2},
+		{name: "Capped at relay count", probes: &portalv1alpha1.TunnelProbes{MinConnectedRelays: ptr.To(int32(3))}, relayCount: 2, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tunnelClass := &portalv1alpha1.TunnelClass{Spec: portalv1alpha1.TunnelClassSpec{Probes: tt.probes}}
+			if got := MinConnectedRelays(tunnelClass, tt.relayCount); got != tt.want {
+				t.Errorf("MinConnectedRelays() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildDeploymentProbes(t *testing.T) {
+	portalExpose := &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: portalv1alpha1.PortalExposeSpec{
+			App: portalv1alpha1.AppSpec{
+				Name:    "test-app",
+				Service: portalv1alpha1.ServiceRef{Name: "test-svc", Port: intstr.FromInt32(80)},
+			},
+			Endpoints: []portalv1alpha1.EndpointSpec{
+				{Name: "grpc", Subdomain: "test-app-grpc", Service: portalv1alpha1.ServiceRef{Name: "test-grpc", Port: intstr.FromInt32(9000)}},
+			},
+		},
+	}
+	tunnelClass := &portalv1alpha1.TunnelClass{
+		Spec: portalv1alpha1.TunnelClassSpec{
+			Replicas: 2,
+			Size:     "small",
+			Probes: &portalv1alpha1.TunnelProbes{
+				MinConnectedRelays: ptr.To(int32(2)),
+				Readiness:          &portalv1alpha1.ProbeThresholds{PeriodSeconds: ptr.To(int32(3))},
+			},
+		},
+	}
+	relays := []RelayEndpoint{
+		{Name: "a", URL: "wss://a.example.com"},
+		{Name: "b", URL: "wss://b.example.com"},
+	}
+
+	deployment := BuildDeployment(portalExpose, tunnelClass, relays, AppEndpoints(portalExpose), DefaultSizes()["small"])
+	for i, container := range deployment.Spec.Template.Spec.Containers {
+		if j := slices.Index(container.Args, "--min-ready-relays"); j < 0 || container.Args[j+1] != "2" {
+			t.Errorf("Container %d args = %v, want --min-ready-relays 2", i, container.Args)
+		}
+
+		readiness := container.ReadinessProbe
+		if readiness == nil || readiness.HTTPGet == nil || readiness.HTTPGet.Path != ReadinessPath {
+			t.Fatalf("Container %d readiness = %+v, want %s", i, readiness, ReadinessPath)
+		}
+		if readiness.HTTPGet.Port != intstr.FromString(container.Ports[0].Name) {
+			t.Errorf("Container %d readiness port = %v, want its status port", i, readiness.HTTPGet.Port.String())
+		}
+		if readiness.PeriodSeconds != 3 || readiness.FailureThreshold != 3 {
+			t.Errorf("Container %d readiness = %+v, want period 3 and the default failure threshold", i, readiness)
+		}
+
+		liveness := container.LivenessProbe
+		if liveness == nil || liveness.HTTPGet == nil || liveness.HTTPGet.Path != LivenessPath || liveness.PeriodSeconds != 20 {
+			t.Errorf("Container %d liveness = %+v, want %s with default thresholds", i, liveness, LivenessPath)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+const (
+	// SecurityOverrideReasonAnnotation records why a TunnelClass relaxes the tunnel pod security defaults
+	// spec.securityOverride is ignored without it
+	SecurityOverrideReasonAnnotation = "portal.gosuda.org/security-override-reason"
+
+	// NonRootUID is the UID tunnel containers run as by default
+	NonRootUID int64 = 65532
+
+	// tmpVolumeName is the writable scratch volume mounted at /tmp, since the root filesystem is read-only
+	tmpVolumeName = "tmp"
+)
+
+// ActiveSecurityOverride returns the security override of the TunnelClass,
+// or nil if it has none or it is not audited with a reason annotation
+func ActiveSecurityOverride(tunnelClass *portalv1alpha1.TunnelClass) *portalv1alpha1.TunnelSecurityOverride {
+	if strings.TrimSpace(tunnelClass.Annotations[SecurityOverrideReasonAnnotation]) == "" {
+		return nil
+	}
+	return tunnelClass.Spec.SecurityOverride
+}
+
+// RelaxedSettings describes each default a security override relaxes, e.g. "runAsNonRoot=false"
+func RelaxedSettings(override *portalv1alpha1.TunnelSecurityOverride) []string {
+	if override == nil {
+		return nil
+	}
+
+	var settings []string
+	if override.RunAsNonRoot != nil && !*override.RunAsNonRoot {
+		settings = append(settings, "runAsNonRoot=false")
+	}
+	if override.RunAsUser != nil {
+		settings = append(settings, fmt.Sprintf("runAsUser=%d", *override.RunAsUser))
+	}
+	if override.ReadOnlyRootFilesystem != nil && !*override.ReadOnlyRootFilesystem {
+		settings = append(settings, "readOnlyRootFilesystem=false")
+	}
+	for _, capability := range override.AddCapabilities {
+		settings = append(settings, "capabilities.add="+string(capability))
+	}
+	if override.SeccompProfile != nil {
+		settings = append(settings, "seccompProfile="+string(override.SeccompProfile.Type))
+	}
+	if override.AutomountServiceAccountToken != nil && *override.AutomountServiceAccountToken {
+		settings = append(settings, "automountServiceAccountToken=true")
+	}
+	return settings
+}
+
+// ViolatesRestricted reports whether pods with the override fail the restricted Pod Security Standard
+// A writable root filesystem and a mounted token are allowed by the standard
+func ViolatesRestricted(override *portalv1alpha1.TunnelSecurityOverride) bool {
+	if override == nil {
+		return false
+	}
+	if override.RunAsNonRoot != nil && !*override.RunAsNonRoot {
+		return true
+	}
+	if override.RunAsUser != nil && *override.RunAsUser == 0 {
+		return true
+	}
+	for _, capability := range override.AddCapabilities {
+		if capability != "NET_BIND_SERVICE" {
+			return true
+		}
+	}
+	return override.SeccompProfile != nil && override.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined
+}
+
+// applySecurityContext sets the restricted Pod Security defaults on the pod and every container,
+// relaxed by the audited override of the TunnelClass if any
+func applySecurityContext(podSpec *corev1.PodSpec, override *portalv1alpha1.TunnelSecurityOverride) {
+	if override == nil {
+		override = &portalv1alpha1.TunnelSecurityOverride{}
+	}
+
+	runAsNonRoot := ptr.Deref(override.RunAsNonRoot, true)
+	seccompProfile := &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+	if override.SeccompProfile != nil {
+		seccompProfile = override.SeccompProfile.DeepCopy()
+	}
+
+	podSpec.AutomountServiceAccountToken = ptr.To(ptr.Deref(override.AutomountServiceAccountToken, false))
+	podSpec.SecurityContext = &corev1.PodSecurityContext{
+		RunAsNonRoot:   ptr.To(runAsNonRoot),
+		RunAsUser:      ptr.To(ptr.Deref(override.RunAsUser, NonRootUID)),
+		RunAsGroup:     ptr.To(NonRootUID),
+		SeccompProfile: seccompProfile,
+	}
+
+	for i := range podSpec.Containers {
+		container := &podSpec.Containers[i]
+		container.SecurityContext = &corev1.SecurityContext{
+			RunAsNonRoot:             ptr.To(runAsNonRoot),
+			AllowPrivilegeEscalation: ptr.To(false),
+			ReadOnlyRootFilesystem:   ptr.To(ptr.Deref(override.ReadOnlyRootFilesystem, true)),
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{"ALL"},
+				Add:  override.AddCapabilities,
+			},
+			SeccompProfile: seccompProfile.DeepCopy(),
+		}
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: tmpVolumeName, MountPath: "/tmp"})
+	}
+
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name:         tmpVolumeName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+}
@@ -0,0 +1,130 @@
+package tunnel
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+func securityTestDeployment(tunnelClass *portalv1alpha1.TunnelClass) corev1.PodSpec {
+	portalExpose := &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: portalv1alpha1.PortalExposeSpec{
+			App: portalv1alpha1.AppSpec{
+				Name:    "test-app",
+				Service: portalv1alpha1.ServiceRef{Name: "test-svc", Port: intstr.FromInt32(80)},
+			},
+		},
+	}
+	deployment := BuildDeployment(portalExpose, tunnelClass, nil, AppEndpoints(portalExpose), DefaultSizes()["small"])
+	return deployment.Spec.Template.Spec
+}
+
+func TestBuildDeploymentRestrictedDefaults(t *testing.T) {
+	podSpec := securityTestDeployment(&portalv1alpha1.TunnelClass{
+		Spec: portalv1alpha1.TunnelClassSpec{Replicas: 1, Size: "small"},
+	})
+
+	if podSpec.AutomountServiceAccountToken == nil || *podSpec.AutomountServiceAccountToken {
+		t.Errorf("AutomountServiceAccountToken = %v, want false", podSpec.AutomountServiceAccountToken)
+	}
+	pod := podSpec.SecurityContext
+	if pod == nil || !ptr.Deref(pod.RunAsNonRoot, false) || ptr.Deref(pod.RunAsUser, 0) != NonRootUID ||
+		pod.SeccompProfile == nil || pod.SeccompProfile.Type != corev1.SeccompProfileTypeRuntimeDefault {
+		t.Errorf("Pod SecurityContext = %+v, want restricted defaults", pod)
+	}
+
+	container := podSpec.Containers[0].SecurityContext
+	if container == nil || !ptr.Deref(container.RunAsNonRoot, false) || ptr.Deref(container.AllowPrivilegeEscalation, true) ||
+		!ptr.Deref(container.ReadOnlyRootFilesystem, false) {
+		t.Fatalf("Container SecurityContext = %+v, want restricted defaults", container)
+	}
+	if container.Capabilities == nil || len(container.Capabilities.Drop) != 1 || container.Capabilities.Drop[0] != "ALL" ||
+		len(container.Capabilities.Add) != 0 {
+		t.Errorf("Capabilities = %+v, want drop ALL", container.Capabilities)
+	}
+	if len(podSpec.Volumes) == 0 || podSpec.Volumes[0].EmptyDir == nil || len(podSpec.Containers[0].VolumeMounts) == 0 ||
+		podSpec.Containers[0].VolumeMounts[0].MountPath != "/tmp" || podSpec.Containers[0].VolumeMounts[0].ReadOnly {
+		t.Errorf("Volumes = %+v, want a writable /tmp", podSpec.Volumes)
+	}
+}
+
+func TestBuildDeploymentSecurityOverride(t *testing.T) {
+	override := &portalv1alpha1.TunnelSecurityOverride{
+		ReadOnlyRootFilesystem:       ptr.To(false),
+		AddCapabilities:              []corev1.Capability{"NET_BIND_SERVICE"},
+		AutomountServiceAccountToken: ptr.To(true),
+	}
+
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		wantOverridden bool
+	}{
+		{
+			name:           "Audited override",
+			annotations:    map[string]string{SecurityOverrideReasonAnnotation: "tunnel writes its cache to /var/cache"},
+			wantOverridden: true,
+		},
+		{
+			name: "Override without reason is ignored",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			podSpec := securityTestDeployment(&portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+				Spec:       portalv1alpha1.TunnelClassSpec{Replicas: 1, Size: "small", SecurityOverride: override},
+			})
+
+			container := podSpec.Containers[0].SecurityContext
+			if got := !ptr.Deref(container.ReadOnlyRootFilesystem, true); got != tt.wantOverridden {
+				t.Errorf("Writable root filesystem = %v, want %v", got, tt.wantOverridden)
+			}
+			if got := len(container.Capabilities.Add) == 1; got != tt.wantOverridden {
+				t.Errorf("Capabilities.Add = %v, want overridden %v", container.Capabilities.Add, tt.wantOverridden)
+			}
+			if got := ptr.Deref(podSpec.AutomountServiceAccountToken, false); got != tt.wantOverridden {
+				t.Errorf("AutomountServiceAccountToken = %v, want %v", got, tt.wantOverridden)
+			}
+			// Untouched defaults stay restricted either way
+			if !ptr.Deref(container.RunAsNonRoot, false) || ptr.Deref(container.AllowPrivilegeEscalation, true) {
+				t.Errorf("Container SecurityContext = %+v, want non-root without privilege escalation", container)
+			}
+		})
+	}
+}
+
+func TestViolatesRestricted(t *testing.T) {
+	tests := []struct {
+		name     string
+		override *portalv1alpha1.TunnelSecurityOverride
+		want     bool
+	}{
+		{name: "No override"},
+		{name: "Writable root filesystem", override: &portalv1alpha1.TunnelSecurityOverride{ReadOnlyRootFilesystem: ptr.To(false)}},
+		{name: "NET_BIND_SERVICE", override: &portalv1alpha1.TunnelSecurityOverride{AddCapabilities: []corev1.Capability{"NET_BIND_SERVICE"}}},
+		{name: "Root", override: &portalv1alpha1.TunnelSecurityOverride{RunAsNonRoot: ptr.To(false)}, want: true},
+		{name: "UID 0", override: &portalv1alpha1.TunnelSecurityOverride{RunAsUser: ptr.To(int64(0))}, want: true},
+		{name: "NET_ADMIN", override: &portalv1alpha1.TunnelSecurityOverride{AddCapabilities: []corev1.Capability{"NET_ADMIN"}}, want: true},
+		{
+			name:     "Unconfined seccomp",
+			override: &portalv1alpha1.TunnelSecurityOverride{SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined}},
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ViolatesRestricted(tt.override); got != tt.want {
+				t.Errorf("ViolatesRestricted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
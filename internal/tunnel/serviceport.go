@@ -0,0 +1,59 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ResolveServicePort resolves a port name or number against the ports of the Service
+// It returns the Service port number the tunnel should forward to
+func ResolveServicePort(service *corev1.Service, port intstr.IntOrString) (int32, error) {
+	for _, servicePort := range service.Spec.Ports {
+		switch port.Type {
+		case intstr.String:
+			if servicePort.Name == port.StrVal {
+				return servicePort.Port, nil
+			}
+		default:
+			if servicePort.Port == port.IntVal {
+				return servicePort.Port, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("port %s not found on Service '%s'", port.String(), service.Name)
+}
+
+// ServiceTargetPort returns the pod port a resolved Service port forwards to
+// An unset targetPort defaults to the Service port, as in the Service API
+func ServiceTargetPort(service *corev1.Service, port int32) intstr.IntOrString {
+	for _, servicePort := range service.Spec.Ports {
+		if servicePort.Port != port {
+			continue
+		}
+		if servicePort.TargetPort.Type == intstr.String && servicePort.TargetPort.StrVal != "" ||
+			servicePort.TargetPort.Type == intstr.Int && servicePort.TargetPort.IntVal != 0 {
+			return servicePort.TargetPort
+		}
+		break
+	}
+	return intstr.FromInt32(port)
+}
@@ -0,0 +1,75 @@
+package tunnel
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestResolveServicePort(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web"},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80, TargetPort: intstr.FromInt32(8080)},
+				{Name: "metrics", Port: 9090},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		port     intstr.IntOrString
+		expected int32
+		wantErr  bool
+	}{
+		{name: "By number", port: intstr.FromInt32(9090), expected: 9090},
+		{name: "By name", port: intstr.FromString("http"), expected: 80},
+		{name: "Target port is not a Service port", port: intstr.FromInt32(8080), wantErr: true},
+		{name: "Unknown name", port: intstr.FromString("grpc"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, err := ResolveServicePort(service, tt.port)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveServicePort() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if port != tt.expected {
+				t.Errorf("ResolveServicePort() = %v, want %v", port, tt.expected)
+			}
+		})
+	}
+}
+
+func TestServiceTargetPort(t *testing.T) {
+	service := &corev1.Service{
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80, TargetPort: intstr.FromInt32(8080)},
+				{Name: "grpc", Port: 9000, TargetPort: intstr.FromString("grpc")},
+				{Name: "metrics", Port: 9090},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		port     int32
+		expected intstr.IntOrString
+	}{
+		{name: "Numeric target port", port: 80, expected: intstr.FromInt32(8080)},
+		{name: "Named target port", port: 9000, expected: intstr.FromString("grpc")},
+		{name: "Unset target port", port: 9090, expected: intstr.FromInt32(9090)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ServiceTargetPort(service, tt.port); got != tt.expected {
+				t.Errorf("ServiceTargetPort() = %v, want %v", got.String(), tt.expected.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+// ErrUnknownSize is returned for a TunnelClass size that is not in the size table
+var ErrUnknownSize = errors.New("unknown size")
+
+// SizeTable maps TunnelClass size tiers to the resources of each tunnel container
+type SizeTable map[string]corev1.ResourceRequirements
+
+// DefaultSizes returns the built-in small, medium and large tiers
+func DefaultSizes() SizeTable {
+	tier := func(cpuRequest, memoryRequest, cpuLimit, memoryLimit string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpuRequest),
+				corev1.ResourceMemory: resource.MustParse(memoryRequest),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpuLimit),
+				corev1.ResourceMemory: resource.MustParse(memoryLimit),
+			},
+		}
+	}
+
+	return SizeTable{
+		"small":  tier("100m", "128Mi", "500m", "512Mi"),
+		"medium": tier("250m", "256Mi", "1000m", "1Gi"),
+		"large":  tier("500m", "512Mi", "2000m", "2Gi"),
+	}
+}
+
+// ParseSizes parses a YAML map of tier name to resource requirements
+// Parsed tiers are added to the built-in tiers and replace built-in tiers of the same name
+func ParseSizes(data []byte) (SizeTable, error) {
+	tiers := SizeTable{}
+	if err := yaml.UnmarshalStrict(data, &tiers); err != nil {
+		return nil, fmt.Errorf("failed to parse size tiers: %w", err)
+	}
+
+	sizes := DefaultSizes()
+	for name, resources := range tiers {
+		if len(resources.Requests) == 0 && len(resources.Limits) == 0 {
+			return nil, fmt.Errorf("size tier %q sets neither requests nor limits", name)
+		}
+		sizes[name] = resources
+	}
+	return sizes, nil
+}
+
+// LoadSizes reads the size tier file at path, usually a mounted ConfigMap
+// An empty path or a missing file yields the built-in tiers
+func LoadSizes(path string) (SizeTable, error) {
+	if path == "" {
+		return DefaultSizes(), nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultSizes(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseSizes(data)
+}
+
+// Names returns the tier names in sorted order
+func (t SizeTable) Names() []string {
+	names := make([]string, 0, len(t))
+	for name := range t {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Resources returns the resources of each tunnel container for a TunnelClass
+// Explicit spec.resources win over spec.size; an unknown size returns ErrUnknownSize
+func (t SizeTable) Resources(tunnelClass *portalv1alpha1.TunnelClass) (corev1.ResourceRequirements, error) {
+	if tunnelClass.Spec.Resources != nil {
+		return *tunnelClass.Spec.Resources.DeepCopy(), nil
+	}
+
+	resources, ok := t[tunnelClass.Spec.Size]
+	if !ok {
+		return corev1.ResourceRequirements{}, fmt.Errorf("%w %q, available sizes are %s",
+			ErrUnknownSize, tunnelClass.Spec.Size, strings.Join(t.Names(), ", "))
+	}
+	return *resources.DeepCopy(), nil
+}
@@ -0,0 +1,103 @@
+package tunnel
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+func TestSizeTableResources(t *testing.T) {
+	explicit := &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m")},
+	}
+
+	tests := []struct {
+		name       string
+		spec       portalv1alpha1.TunnelClassSpec
+		wantCPU    string
+		wantErrIs  error
+		wantLimits bool
+	}{
+		{name: "Built-in tier", spec: portalv1alpha1.TunnelClassSpec{Size: "medium"}, wantCPU: "250m", wantLimits: true},
+		{name: "Explicit resources", spec: portalv1alpha1.TunnelClassSpec{Resources: explicit}, wantCPU: "50m"},
+		{name: "Unknown tier", spec: portalv1alpha1.TunnelClassSpec{Size: "xlarge"}, wantErrIs: ErrUnknownSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources, err := DefaultSizes().Resources(&portalv1alpha1.TunnelClass{Spec: tt.spec})
+			if tt.wantErrIs != nil {
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Errorf("Resources() error = %v, want %v", err, tt.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resources() error = %v", err)
+			}
+			if cpu := resources.Requests[corev1.ResourceCPU]; cpu.String() != tt.wantCPU {
+				t.Errorf("Resources() cpu request = %v, want %v", cpu.String(), tt.wantCPU)
+			}
+			if (len(resources.Limits) > 0) != tt.wantLimits {
+				t.Errorf("Resources() limits = %v, want limits %v", resources.Limits, tt.wantLimits)
+			}
+		})
+	}
+}
+
+func TestParseSizes(t *testing.T) {
+	sizes, err := ParseSizes([]byte(`
+xlarge:
+  requests:
+    cpu: "1"
+    memory: 1Gi
+  limits:
+    cpu: "4"
+    memory: 4Gi
+small:
+  requests:
+    cpu: 50m
+    memory: 64Mi
+`))
+	if err != nil {
+		t.Fatalf("ParseSizes() error = %v", err)
+	}
+
+	if got := sizes.Names(); len(got) != 4 || got[3] != "xlarge" {
+		t.Errorf("Names() = %v, want built-in tiers plus xlarge", got)
+	}
+	if cpu := sizes["xlarge"].Limits[corev1.ResourceCPU]; cpu.String() != "4" {
+		t.Errorf("xlarge cpu limit = %v, want 4", cpu.String())
+	}
+	if cpu := sizes["small"].Requests[corev1.ResourceCPU]; cpu.String() != "50m" {
+		t.Errorf("small cpu request = %v, want the overridden 50m", cpu.String())
+	}
+
+	for _, invalid := range []string{"xlarge: {}", "xlarge:\n  request:\n    cpu: 1", "- small"} {
+		if _, err := ParseSizes([]byte(invalid)); err == nil {
+			t.Errorf("ParseSizes(%q) expected error", invalid)
+		}
+	}
+}
+
+func TestLoadSizes(t *testing.T) {
+	sizes, err := LoadSizes(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil || len(sizes) != 3 {
+		t.Errorf("LoadSizes() = %v, %v, want the built-in tiers for a missing file", sizes.Names(), err)
+	}
+
+	path := filepath.Join(t.TempDir(), "sizes.yaml")
+	if err := os.WriteFile(path, []byte("xlarge:\n  requests:\n    cpu: \"1\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sizes, err = LoadSizes(path)
+	if err != nil || len(sizes) != 4 {
+		t.Errorf("LoadSizes() = %v, %v, want 4 tiers", sizes.Names(), err)
+	}
+}
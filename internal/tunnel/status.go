@@ -17,6 +17,7 @@ limitations under the License.
 package tunnel
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
@@ -28,22 +29,26 @@ import (
 // Extracts domain from relay WSS URL like "wss://portal.gosuda.org/relay" -> "portal.gosuda.org"
 // Returns "https://{app-name}.{relay-domain}"
 func ConstructPublicURL(appName string, relayURL string) string {
-	// Extract domain from WSS URL
+	return "https://" + appName + "." + RelayDomain(relayURL)
+}
+
+// RelayDomain extracts the domain from a relay WSS URL
+// e.g. "wss://portal.gosuda.org/relay" -> "portal.gosuda.org"
+func RelayDomain(relayURL string) string {
 	// Pattern: wss://{domain}/{path}
-	re := regexp.MustCompile(`^wss://([^/]+)`)
-	matches := re.FindStringSubmatch(relayURL)
+	matches := relayDomainPattern.FindStringSubmatch(relayURL)
 
 	if len(matches) < 2 {
 		// Fallback if regex fails
 		domain := strings.TrimPrefix(relayURL, "wss://")
-		domain = strings.Split(domain, "/")[0]
-		return "https://" + appName + "." + domain
+		return strings.Split(domain, "/")[0]
 	}
 
-	domain := matches[1]
-	return "https://" + appName + "." + domain
+	return matches[1]
 }
 
+var relayDomainPattern = regexp.MustCompile(`^wss://([^/]+)`)
+
 // ComputePhase determines the phase based on pod readiness and relay connectivity
 // Phases: Pending | Ready | Degraded | Failed
 func ComputePhase(readyPods, totalPods int32, relayConnected, totalRelays int) string {
@@ -73,22 +78,58 @@ func ComputePhase(readyPods, totalPods int32, relayConnected, totalRelays int) s
 	return util.PhaseFailed
 }
 
-// ComputeRelayStatuses generates relay connection statuses
-// In MVP, this is simplified - assumes Connected if pods are ready
-// Real implementation would read status from tunnel pod annotations/logs
-func ComputeRelayStatuses(relayTargets []portalv1alpha1.RelayTarget, podsReady bool) []portalv1alpha1.RelayConnectionStatus {
-	statuses := make([]portalv1alpha1.RelayConnectionStatus, 0, len(relayTargets))
+// ComputeRelayStatuses aggregates the relay sessions reported by tunnel pods
+// into one status per relay target. A relay is Connected when at least one pod
+// holds an established session to it; ConnectedAt is the earliest such session.
+// A relay is Unknown when no pod status endpoint could be queried, and
+// Disconnected otherwise (including when no tunnel pods are running).
+func ComputeRelayStatuses(relays []RelayEndpoint, reports []PodRelayReport) []portalv1alpha1.RelayConnectionStatus {
+	statuses := make([]portalv1alpha1.RelayConnectionStatus, 0, len(relays))
 
-	for _, target := range relayTargets {
+	for _, target := range relays {
 		status := portalv1alpha1.RelayConnectionStatus{
-			Name: target.Name,
+			Name:   target.Name,
+			Status: util.RelayStatusDisconnected,
+		}
+
+		answered := 0
+		var sessionErr, queryErr string
+		for _, report := range reports {
+			if report.Err != nil {
+				if queryErr == "" {
+					queryErr = report.Err.Error()
+				}
+				continue
+			}
+			answered++
+
+			session := findSession(report.Sessions, target.URL)
+			if session == nil {
+				if sessionErr == "" {
+					sessionErr = fmt.Sprintf("pod %s reports no session for this relay", report.PodName)
+				}
+				continue
+			}
+
+			if session.Connected {
+				status.Status = util.RelayStatusConnected
+				if session.ConnectedAt != nil &&
+					(status.ConnectedAt == nil || session.ConnectedAt.Before(status.ConnectedAt)) {
+					status.ConnectedAt = session.ConnectedAt.DeepCopy()
+				}
+			} else if sessionErr == "" && session.LastError != "" {
+				sessionErr = fmt.Sprintf("pod %s: %s", report.PodName, session.LastError)
+			}
 		}
 
-		if podsReady {
-			status.Status = "Connected"
-			// ConnectedAt would be set here in real implementation
-		} else {
-			status.Status = "Disconnected"
+		switch {
+		case status.Status == util.RelayStatusConnected:
+			// Connected through at least one pod, errors from other pods are not surfaced
+		case answered == 0 && queryErr != "":
+			status.Status = util.RelayStatusUnknown
+			status.LastError = queryErr
+		default:
+			status.LastError = sessionErr
 		}
 
 		statuses = append(statuses, status)
@@ -96,3 +137,13 @@ func ComputeRelayStatuses(relayTargets []portalv1alpha1.RelayTarget, podsReady b
 
 	return statuses
 }
+
+// findSession returns the session opened against relayURL, or nil if absent
+func findSession(sessions []RelaySession, relayURL string) *RelaySession {
+	for i := range sessions {
+		if sessions[i].URL == relayURL {
+			return &sessions[i]
+		}
+	}
+	return nil
+}
@@ -1,9 +1,11 @@
 package tunnel
 
 import (
+	"errors"
 	"testing"
+	"time"
 
-	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestConstructPublicURL(t *testing.T) {
@@ -42,6 +44,13 @@ func TestConstructPublicURL(t *testing.T) {
 	}
 }
 
+func TestRelayEndpointPublicURL(t *testing.T) {
+	endpoint := RelayEndpoint{Name: "shared", URL: "wss://relay-eu.gosuda.org/relay", PublicDomain: "portal.gosuda.org"}
+	if got, want := endpoint.PublicURL("my-app"), "https://my-app.portal.gosuda.org"; got != want {
+		t.Errorf("PublicURL() = %v, want %v", got, want)
+	}
+}
+
 func TestComputePhase(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -75,6 +84,14 @@ func TestComputePhase(t *testing.T) {
 			totalRelays:    1,
 			want:           "Degraded",
 		},
+		{
+			name:           "Degraded (Pods ready, one of two relays down)",
+			readyPods:      2,
+			totalPods:      2,
+			relayConnected: 1,
+			totalRelays:    2,
+			want:           "Degraded",
+		},
 		{
 			name:           "Degraded (Pod not ready, Relay connected - theoretical)",
 			readyPods:      0,
@@ -103,37 +120,87 @@ func TestComputePhase(t *testing.T) {
 }
 
 func TestComputeRelayStatuses(t *testing.T) {
-	targets := []portalv1alpha1.RelayTarget{
-		{Name: "relay1"},
-		{Name: "relay2"},
+	targets := []RelayEndpoint{
+		{Name: "relay1", URL: "wss://relay1.example.com/relay"},
+		{Name: "relay2", URL: "wss://relay2.example.com/relay"},
 	}
+	earlier := metav1.NewTime(time.Date(2025, 1, 14, 10, 30, 0, 0, time.UTC))
+	later := metav1.NewTime(earlier.Add(time.Minute))
 
 	tests := []struct {
-		name      string
-		podsReady bool
-		wantState string
+		name          string
+		reports       []PodRelayReport
+		wantStates    []string
+		wantConnected []*metav1.Time
+		wantErrors    []string
 	}{
 		{
-			name:      "Pods Ready -> Connected",
-			podsReady: true,
-			wantState: "Connected",
+			name:       "No running pods -> Disconnected",
+			reports:    nil,
+			wantStates: []string{"Disconnected", "Disconnected"},
+			wantErrors: []string{"", ""},
 		},
 		{
-			name:      "Pods Not Ready -> Disconnected",
-			podsReady: false,
-			wantState: "Disconnected",
+			name: "All sessions established -> Connected with earliest time",
+			reports: []PodRelayReport{
+				{PodName: "pod-a", Sessions: []RelaySession{
+					{URL: targets[0].URL, Connected: true, ConnectedAt: &later},
+					{URL: targets[1].URL, Connected: true, ConnectedAt: &later},
+				}},
+				{PodName: "pod-b", Sessions: []RelaySession{
+					{URL: targets[0].URL, Connected: true, ConnectedAt: &earlier},
+					{URL: targets[1].URL, Connected: false, LastError: "dial timeout"},
+				}},
+			},
+			wantStates:    []string{"Connected", "Connected"},
+			wantConnected: []*metav1.Time{&earlier, &later},
+			wantErrors:    []string{"", ""},
+		},
+		{
+			name: "One relay down -> Disconnected with error",
+			reports: []PodRelayReport{
+				{PodName: "pod-a", Sessions: []RelaySession{
+					{URL: targets[0].URL, Connected: true, ConnectedAt: &earlier},
+					{URL: targets[1].URL, Connected: false, LastError: "handshake failed"},
+				}},
+			},
+			wantStates:    []string{"Connected", "Disconnected"},
+			wantConnected: []*metav1.Time{&earlier, nil},
+			wantErrors:    []string{"", "pod pod-a: handshake failed"},
+		},
+		{
+			name: "Status endpoint unreachable -> Unknown",
+			reports: []PodRelayReport{
+				{PodName: "pod-a", Err: errors.New("connection refused")},
+			},
+			wantStates: []string{"Unknown", "Unknown"},
+			wantErrors: []string{"connection refused", "connection refused"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			statuses := ComputeRelayStatuses(targets, tt.podsReady)
-			if len(statuses) != 2 {
-				t.Errorf("Expected 2 statuses, got %d", len(statuses))
+			statuses := ComputeRelayStatuses(targets, tt.reports)
+			if len(statuses) != len(targets) {
+				t.Fatalf("Expected %d statuses, got %d", len(targets), len(statuses))
 			}
-			for _, s := range statuses {
-				if s.Status != tt.wantState {
-					t.Errorf("Status = %v, want %v", s.Status, tt.wantState)
+			for i, s := range statuses {
+				if s.Name != targets[i].Name {
+					t.Errorf("Name = %v, want %v", s.Name, targets[i].Name)
+				}
+				if s.Status != tt.wantStates[i] {
+					t.Errorf("%s: Status = %v, want %v", s.Name, s.Status, tt.wantStates[i])
+				}
+				if s.LastError != tt.wantErrors[i] {
+					t.Errorf("%s: LastError = %q, want %q", s.Name, s.LastError, tt.wantErrors[i])
+				}
+				var want *metav1.Time
+				if tt.wantConnected != nil {
+					want = tt.wantConnected[i]
+				}
+				if (s.ConnectedAt == nil) != (want == nil) ||
+					(want != nil && !s.ConnectedAt.Equal(want)) {
+					t.Errorf("%s: ConnectedAt = %v, want %v", s.Name, s.ConnectedAt, want)
 				}
 			}
 		})
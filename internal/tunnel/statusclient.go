@@ -0,0 +1,210 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// StatusPort is the port the tunnel container serves its status endpoint on
+	StatusPort int32 = 8090
+
+	// StatusPortName is the container port name of the status endpoint
+	StatusPortName = "status"
+
+	// StatusPath is the HTTP path reporting per-relay session state
+	StatusPath = "/status"
+
+	// DefaultStatusTimeout bounds a single status request to a tunnel pod
+	DefaultStatusTimeout = 3 * time.Second
+)
+
+// RelaySession is the state of one relay session as reported by a tunnel pod
+type RelaySession struct {
+	// URL is the relay URL the session was opened against (matches --relay)
+	URL string `json:"url"`
+
+	// Connected reports whether the session is currently established
+	Connected bool `json:"connected"`
+
+	// ConnectedAt is when the current session was established
+	ConnectedAt *metav1.Time `json:"connectedAt,omitempty"`
+
+	// LastError is the most recent session error, if any
+	LastError string `json:"lastError,omitempty"`
+}
+
+// StatusReport is the payload served by the tunnel status endpoint
+type StatusReport struct {
+	Relays []RelaySession `json:"relays"`
+}
+
+// PodRelayReport holds the relay sessions collected from a single tunnel pod
+type PodRelayReport struct {
+	// PodName is the name of the tunnel pod that was queried
+	PodName string
+
+	// Sessions are the relay sessions reported by the pod
+	Sessions []RelaySession
+
+	// Err is set when the pod's status endpoint could not be queried
+	Err error
+}
+
+// StatusClient queries a tunnel pod for its relay session state
+type StatusClient interface {
+	RelaySessions(ctx context.Context, pod *corev1.Pod) ([]RelaySession, error)
+}
+
+// HTTPStatusClient reads relay session state from the tunnel pod status endpoint
+type HTTPStatusClient struct {
+	// HTTPClient is used for requests; its Timeout bounds each request
+	HTTPClient *http.Client
+
+	// Port is the status endpoint port of the first tunnel container on the pod IP
+	// Further tunnel containers are queried at the same offset from Port as from StatusPort
+	Port int32
+}
+
+// NewHTTPStatusClient returns a StatusClient using the default status port and timeout
+func NewHTTPStatusClient() *HTTPStatusClient {
+	return &HTTPStatusClient{
+		HTTPClient: &http.Client{Timeout: DefaultStatusTimeout},
+		Port:       StatusPort,
+	}
+}
+
+// RelaySessions fetches the relay sessions reported by the given pod
+// Pods carrying several tunnel containers report a relay as connected only when every container holds a session to it
+func (c *HTTPStatusClient) RelaySessions(ctx context.Context, pod *corev1.Pod) ([]RelaySession, error) {
+	if pod.Status.PodIP == "" {
+		return nil, fmt.Errorf("pod %s has no IP assigned", pod.Name)
+	}
+
+	var merged []RelaySession
+	for i, offset := range statusPortOffsets(pod) {
+		sessions, err := c.containerRelaySessions(ctx, pod, c.Port+offset)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			merged = sessions
+			continue
+		}
+		merged = mergeRelaySessions(merged, sessions)
+	}
+	return merged, nil
+}
+
+// containerRelaySessions fetches the relay sessions of the tunnel container serving status on port
+func (c *HTTPStatusClient) containerRelaySessions(ctx context.Context, pod *corev1.Pod, port int32) ([]RelaySession, error) {
+	url := fmt.Sprintf("http://%s:%d%s", pod.Status.PodIP, port, StatusPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build status request for pod %s: %w", pod.Name, err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status of pod %s: %w", pod.Name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status endpoint of pod %s returned %s", pod.Name, resp.Status)
+	}
+
+	report := &StatusReport{}
+	if err := json.NewDecoder(resp.Body).Decode(report); err != nil {
+		return nil, fmt.Errorf("failed to decode status of pod %s: %w", pod.Name, err)
+	}
+	return report.Relays, nil
+}
+
+// statusPortOffsets returns the offset from StatusPort of every tunnel container status port
+// Pods that declare no status port are queried at offset 0
+func statusPortOffsets(pod *corev1.Pod) []int32 {
+	var offsets []int32
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == StatusPortName || strings.HasPrefix(port.Name, StatusPortName+"-") {
+				offsets = append(offsets, port.ContainerPort-StatusPort)
+			}
+		}
+	}
+	if len(offsets) == 0 {
+		return []int32{0}
+	}
+	return offsets
+}
+
+// mergeRelaySessions keeps the sessions of a that b also holds; a relay missing
+// or disconnected in b is reported disconnected with b's error
+func mergeRelaySessions(a, b []RelaySession) []RelaySession {
+	merged := make([]RelaySession, 0, len(a))
+	for _, session := range a {
+		other := findSession(b, session.URL)
+		switch {
+		case other == nil:
+			session.Connected = false
+			session.ConnectedAt = nil
+			if session.LastError == "" {
+				session.LastError = "not every endpoint holds a session to this relay"
+			}
+		case !other.Connected:
+			session.Connected = false
+			session.ConnectedAt = nil
+			session.LastError = other.LastError
+		case session.ConnectedAt != nil && other.ConnectedAt != nil && other.ConnectedAt.After(session.ConnectedAt.Time):
+			// The relay serves every endpoint only since the latest session came up
+			session.ConnectedAt = other.ConnectedAt
+		}
+		merged = append(merged, session)
+	}
+	return merged
+}
+
+// CollectRelayReports queries every running tunnel pod for its relay sessions
+// Pods that are not running or have no IP yet are skipped
+func CollectRelayReports(ctx context.Context, c StatusClient, pods []corev1.Pod) []PodRelayReport {
+	reports := make([]PodRelayReport, 0, len(pods))
+
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		sessions, err := c.RelaySessions(ctx, pod)
+		reports = append(reports, PodRelayReport{
+			PodName:  pod.Name,
+			Sessions: sessions,
+			Err:      err,
+		})
+	}
+
+	return reports
+}
@@ -0,0 +1,135 @@
+package tunnel
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newFakeStatusServer starts an in-process tunnel status endpoint serving report
+func newFakeStatusServer(t *testing.T, report StatusReport) (*httptest.Server, int32) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != StatusPath {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	}))
+	t.Cleanup(server.Close)
+
+	_, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to parse server address: %v", err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		t.Fatalf("failed to parse server port: %v", err)
+	}
+	return server, int32(port)
+}
+
+func runningPod(name, ip string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: ip},
+	}
+}
+
+func TestHTTPStatusClientRelaySessions(t *testing.T) {
+	connectedAt := metav1.Now().Rfc3339Copy()
+	_, port := newFakeStatusServer(t, StatusReport{
+		Relays: []RelaySession{
+			{URL: "wss://relay1.example.com/relay", Connected: true, ConnectedAt: &connectedAt},
+			{URL: "wss://relay2.example.com/relay", Connected: false, LastError: "dial timeout"},
+		},
+	})
+
+	c := NewHTTPStatusClient()
+	c.Port = port
+
+	pod := runningPod("tunnel-0", "127.0.0.1")
+	sessions, err := c.RelaySessions(context.Background(), &pod)
+	if err != nil {
+		t.Fatalf("RelaySessions() error = %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+	}
+	if !sessions[0].Connected || !sessions[0].ConnectedAt.Equal(&connectedAt) {
+		t.Errorf("Session[0] = %+v, want connected at %v", sessions[0], connectedAt)
+	}
+	if sessions[1].Connected || sessions[1].LastError != "dial timeout" {
+		t.Errorf("Session[1] = %+v, want disconnected with error", sessions[1])
+	}
+}
+
+func TestHTTPStatusClientNoPodIP(t *testing.T) {
+	pod := runningPod("tunnel-0", "")
+	if _, err := NewHTTPStatusClient().RelaySessions(context.Background(), &pod); err == nil {
+		t.Error("RelaySessions() expected error for pod without IP")
+	}
+}
+
+func TestCollectRelayReports(t *testing.T) {
+	_, port := newFakeStatusServer(t, StatusReport{
+		Relays: []RelaySession{{URL: "wss://relay1.example.com/relay", Connected: true}},
+	})
+
+	c := NewHTTPStatusClient()
+	c.Port = port
+
+	pending := runningPod("tunnel-pending", "127.0.0.1")
+	pending.Status.Phase = corev1.PodPending
+
+	pods := []corev1.Pod{
+		runningPod("tunnel-0", "127.0.0.1"),
+		pending,
+		runningPod("tunnel-no-ip", ""),
+	}
+
+	reports := CollectRelayReports(context.Background(), c, pods)
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 report, got %d", len(reports))
+	}
+	if reports[0].PodName != "tunnel-0" || reports[0].Err != nil || len(reports[0].Sessions) != 1 {
+		t.Errorf("Report = %+v, want one session from tunnel-0", reports[0])
+	}
+}
+
+func TestMergeRelaySessions(t *testing.T) {
+	earlier := metav1.NewTime(time.Now().Add(-time.Minute))
+	later := metav1.NewTime(time.Now())
+
+	app := []RelaySession{
+		{URL: "wss://relay1.example.com/relay", Connected: true, ConnectedAt: &earlier},
+		{URL: "wss://relay2.example.com/relay", Connected: true, ConnectedAt: &earlier},
+		{URL: "wss://relay3.example.com/relay", Connected: true, ConnectedAt: &earlier},
+	}
+	admin := []RelaySession{
+		{URL: "wss://relay1.example.com/relay", Connected: true, ConnectedAt: &later},
+		{URL: "wss://relay2.example.com/relay", Connected: false, LastError: "dial timeout"},
+	}
+
+	merged := mergeRelaySessions(app, admin)
+	if len(merged) != 3 {
+		t.Fatalf("Expected 3 sessions, got %d", len(merged))
+	}
+	if !merged[0].Connected || !merged[0].ConnectedAt.Equal(&later) {
+		t.Errorf("Session[0] = %+v, want connected at the later time", merged[0])
+	}
+	if merged[1].Connected || merged[1].LastError != "dial timeout" {
+		t.Errorf("Session[1] = %+v, want disconnected with the admin error", merged[1])
+	}
+	if merged[2].Connected || merged[2].LastError == "" {
+		t.Errorf("Session[2] = %+v, want disconnected when an endpoint has no session", merged[2])
+	}
+}
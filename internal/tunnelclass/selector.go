@@ -2,8 +2,14 @@ package tunnelclass
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
@@ -11,42 +17,109 @@ import (
 
 const (
 	DefaultClassAnnotation = "portal.gosuda.org/is-default-class"
+
+	// NamespaceDefaultClassAnnotation on a Namespace names the TunnelClass its PortalExposes use without a tunnelClassName
+	NamespaceDefaultClassAnnotation = "portal.gosuda.org/default-tunnel-class"
 )
 
-// GetTunnelClass returns the TunnelClass to use for a PortalExpose
+// ErrNamespaceNotAllowed is returned for a TunnelClass whose allowedNamespaces do not select the namespace
+var ErrNamespaceNotAllowed = errors.New("namespace not allowed")
+
+// ErrNoDefaultTunnelClass is returned when no TunnelClass is marked as default
+var ErrNoDefaultTunnelClass = errors.New("no default TunnelClass found")
+
+// GetTunnelClass returns the TunnelClass to use for a PortalExpose in the namespace
 // It follows this priority:
 // 1. Explicit spec.tunnelClassName if set
-// 2. Default TunnelClass (annotated with portal.gosuda.org/is-default-class: "true")
-// 3. Error if no default exists
-func GetTunnelClass(ctx context.Context, c client.Client, tunnelClassName string) (*portalv1alpha1.TunnelClass, error) {
-	// If explicit TunnelClass name provided, fetch it
-	if tunnelClassName != "" {
-		tunnelClass := &portalv1alpha1.TunnelClass{}
-		key := client.ObjectKey{Name: tunnelClassName}
-		if err := c.Get(ctx, key, tunnelClass); err != nil {
-			return nil, fmt.Errorf("failed to get TunnelClass %q: %w", tunnelClassName, err)
+// 2. The class named by the namespace's portal.gosuda.org/default-tunnel-class annotation
+// 3. Default TunnelClass (annotated with portal.gosuda.org/is-default-class: "true")
+// 4. Error if no default exists
+// The class must allow the namespace
+func GetTunnelClass(ctx context.Context, c client.Client, namespace, tunnelClassName string) (*portalv1alpha1.TunnelClass, error) {
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return nil, fmt.Errorf("failed to get Namespace %q: %w", namespace, err)
+	}
+
+	name := tunnelClassName
+	if name == "" {
+		name = ns.Annotations[NamespaceDefaultClassAnnotation]
+	}
+
+	var tunnelClass *portalv1alpha1.TunnelClass
+	if name != "" {
+		// Fetch the explicit or namespace default TunnelClass
+		tunnelClass = &portalv1alpha1.TunnelClass{}
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, tunnelClass); err != nil {
+			return nil, fmt.Errorf("failed to get TunnelClass %q: %w", name, err)
+		}
+	} else {
+		// Otherwise, find the default TunnelClass
+		var err error
+		if tunnelClass, err = GetDefaultTunnelClass(ctx, c); err != nil {
+			return nil, err
 		}
-		return tunnelClass, nil
 	}
 
-	// Otherwise, find the default TunnelClass
-	return GetDefaultTunnelClass(ctx, c)
+	allowed, err := AllowsNamespace(tunnelClass, ns)
+	if err != nil {
+		return nil, fmt.Errorf("TunnelClass %q has invalid allowedNamespaces: %w", tunnelClass.Name, err)
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w: TunnelClass %q does not select namespace %q", ErrNamespaceNotAllowed, tunnelClass.Name, namespace)
+	}
+	return tunnelClass, nil
+}
+
+// AllowsNamespace reports whether PortalExposes in the namespace may use the TunnelClass
+// Classes without allowedNamespaces are available to every namespace
+func AllowsNamespace(tunnelClass *portalv1alpha1.TunnelClass, namespace *corev1.Namespace) (bool, error) {
+	if tunnelClass.Spec.AllowedNamespaces == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(tunnelClass.Spec.AllowedNamespaces)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(namespace.Labels)), nil
 }
 
 // GetDefaultTunnelClass finds the TunnelClass marked as default
+// The webhook admits a single default, but concurrent admissions can still mark two;
+// the oldest one wins so every caller resolves the same class
 func GetDefaultTunnelClass(ctx context.Context, c client.Client) (*portalv1alpha1.TunnelClass, error) {
 	tunnelClasses := &portalv1alpha1.TunnelClassList{}
 	if err := c.List(ctx, tunnelClasses); err != nil {
 		return nil, fmt.Errorf("failed to list TunnelClasses: %w", err)
 	}
 
-	// Find the default
-	for i := range tunnelClasses.Items {
-		tc := &tunnelClasses.Items[i]
-		if tc.Annotations != nil && tc.Annotations[DefaultClassAnnotation] == "true" {
-			return tc, nil
+	if defaults := DefaultTunnelClasses(tunnelClasses.Items); len(defaults) > 0 {
+		return defaults[0], nil
+	}
+
+	return nil, fmt.Errorf("%w (annotate one with %s: \"true\")", ErrNoDefaultTunnelClass, DefaultClassAnnotation)
+}
+
+// DefaultTunnelClasses returns the TunnelClasses marked as default, the selected default first
+// Ties are broken by the oldest creationTimestamp, then by name
+func DefaultTunnelClasses(tunnelClasses []portalv1alpha1.TunnelClass) []*portalv1alpha1.TunnelClass {
+	var defaults []*portalv1alpha1.TunnelClass
+	for i := range tunnelClasses {
+		if IsDefault(&tunnelClasses[i]) {
+			defaults = append(defaults, &tunnelClasses[i])
 		}
 	}
 
-	return nil, fmt.Errorf("no default TunnelClass found (annotate one with %s: \"true\")", DefaultClassAnnotation)
+	slices.SortFunc(defaults, func(a, b *portalv1alpha1.TunnelClass) int {
+		if c := a.CreationTimestamp.Compare(b.CreationTimestamp.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return defaults
+}
+
+// IsDefault reports whether the TunnelClass is annotated as the default class
+func IsDefault(tunnelClass *portalv1alpha1.TunnelClass) bool {
+	return tunnelClass.Annotations[DefaultClassAnnotation] == "true"
 }
@@ -0,0 +1,168 @@
+package tunnelclass
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+func TestDefaultTunnelClasses(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tunnelClass := func(name string, age time.Duration, isDefault bool) portalv1alpha1.TunnelClass {
+		tc := portalv1alpha1.TunnelClass{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created.Add(-age))},
+		}
+		if isDefault {
+			tc.Annotations = map[string]string{DefaultClassAnnotation: "true"}
+		}
+		return tc
+	}
+
+	tests := []struct {
+		name    string
+		classes []portalv1alpha1.TunnelClass
+		want    []string
+	}{
+		{
+			name:    "no default",
+			classes: []portalv1alpha1.TunnelClass{tunnelClass("standard", time.Hour, false)},
+		},
+		{
+			name: "single default",
+			classes: []portalv1alpha1.TunnelClass{
+				tunnelClass("standard", time.Hour, false),
+				tunnelClass("shared", time.Minute, true),
+			},
+			want: []string{"shared"},
+		},
+		{
+			name: "oldest default first",
+			classes: []portalv1alpha1.TunnelClass{
+				tunnelClass("newer", time.Minute, true),
+				tunnelClass("older", time.Hour, true),
+			},
+			want: []string{"older", "newer"},
+		},
+		{
+			name: "name breaks ties",
+			classes: []portalv1alpha1.TunnelClass{
+				tunnelClass("zeta", time.Hour, true),
+				tunnelClass("alpha", time.Hour, true),
+			},
+			want: []string{"alpha", "zeta"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, tc := range DefaultTunnelClasses(tt.classes) {
+				got = append(got, tc.Name)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("DefaultTunnelClasses() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("DefaultTunnelClasses() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestAllowsNamespace(t *testing.T) {
+	tenant := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", Labels: map[string]string{"tenant": "a"}}}
+	other := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+
+	tests := []struct {
+		name      string
+		selector  *metav1.LabelSelector
+		namespace *corev1.Namespace
+		want      bool
+		wantErr   bool
+	}{
+		{name: "unset allows every namespace", namespace: other, want: true},
+		{name: "empty selector allows every namespace", selector: &metav1.LabelSelector{}, namespace: other, want: true},
+		{
+			name:      "matching labels",
+			selector:  &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+			namespace: tenant,
+			want:      true,
+		},
+		{
+			name:      "other namespace",
+			selector:  &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+			namespace: other,
+		},
+		{
+			name: "invalid selector",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "tenant", Operator: "Matches"},
+			}},
+			namespace: tenant,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tunnelClass := &portalv1alpha1.TunnelClass{Spec: portalv1alpha1.TunnelClassSpec{AllowedNamespaces: tt.selector}}
+			got, err := AllowsNamespace(tunnelClass, tt.namespace)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AllowsNamespace() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("AllowsNamespace() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTunnelClassErrors(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add client-go scheme: %v", err)
+	}
+	if err := portalv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add portal scheme: %v", err)
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+	tenantClass := &portalv1alpha1.TunnelClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "tenant"},
+		Spec: portalv1alpha1.TunnelClassSpec{
+			AllowedNamespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(namespace, tenantClass).Build()
+
+	tests := []struct {
+		name      string
+		className string
+		is        func(error) bool
+	}{
+		{name: "missing class", className: "missing", is: apierrors.IsNotFound},
+		{name: "no default class", is: func(err error) bool { return errors.Is(err, ErrNoDefaultTunnelClass) }},
+		{name: "namespace not allowed", className: "tenant", is: func(err error) bool { return errors.Is(err, ErrNamespaceNotAllowed) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GetTunnelClass(context.Background(), c, "apps", tt.className)
+			if err == nil || !tt.is(err) {
+				t.Errorf("GetTunnelClass() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnelclass
+
+import (
+	"strings"
+
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/tunnel"
+)
+
+// ValidateSpec checks the parts of a TunnelClass that the CRD schema cannot express
+// It is shared by the admission webhook and the Valid condition of the TunnelClass controller
+func ValidateSpec(tunnelClass *portalv1alpha1.TunnelClass) field.ErrorList {
+	var allErrs field.ErrorList
+
+	// The pod template overlay may not touch controller-owned labels and annotations
+	allErrs = append(allErrs, tunnel.ValidatePodTemplate(tunnelClass.Spec.PodTemplate, field.NewPath("spec", "podTemplate"))...)
+	allErrs = append(allErrs, tunnel.ValidateNetworkPolicy(tunnelClass.Spec.NetworkPolicy, field.NewPath("spec", "networkPolicy"))...)
+
+	allErrs = append(allErrs, metav1validation.ValidateLabelSelector(tunnelClass.Spec.AllowedNamespaces,
+		metav1validation.LabelSelectorValidationOptions{}, field.NewPath("spec", "allowedNamespaces"))...)
+
+	// Relaxing the security defaults must be justified, so the override is auditable
+	if tunnelClass.Spec.SecurityOverride != nil &&
+		strings.TrimSpace(tunnelClass.Annotations[tunnel.SecurityOverrideReasonAnnotation]) == "" {
+		allErrs = append(allErrs, field.Required(
+			field.NewPath("metadata", "annotations").Key(tunnel.SecurityOverrideReasonAnnotation),
+			"spec.securityOverride requires a reason"))
+	}
+
+	return allErrs
+}
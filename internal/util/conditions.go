@@ -30,6 +30,13 @@ const (
 	PhaseFailed   = "Failed"
 )
 
+// Relay connection status constants for RelayConnectionStatus.Status
+const (
+	RelayStatusConnected    = "Connected"
+	RelayStatusDisconnected = "Disconnected"
+	RelayStatusUnknown      = "Unknown"
+)
+
 // Condition type constants
 const (
 	// ConditionAvailable indicates the PortalExpose is Ready or Degraded
@@ -46,11 +53,36 @@ const (
 
 	// ConditionServiceExists indicates the referenced Service was found
 	ConditionServiceExists = "ServiceExists"
+
+	// ConditionRelaysResolved indicates all relay targets resolved to an endpoint
+	ConditionRelaysResolved = "RelaysResolved"
+
+	// ConditionServicePortResolved indicates the Service port name or number exists on the Service
+	ConditionServicePortResolved = "ServicePortResolved"
+
+	// ConditionCredentialsMissing indicates a relay credentials Secret does not exist
+	ConditionCredentialsMissing = "CredentialsMissing"
+
+	// ConditionValid indicates a TunnelClass spec passed validation
+	ConditionValid = "Valid"
+
+	// ConditionInvalidSize indicates a TunnelClass names a size tier the controller does not know
+	ConditionInvalidSize = "InvalidSize"
+
+	// ConditionRestrictedPodSecurity indicates tunnel pods of a TunnelClass meet the restricted Pod Security Standard
+	ConditionRestrictedPodSecurity = "RestrictedPodSecurity"
+
+	// ConditionDefaultConflict indicates a TunnelClass is marked default but an older default class is selected
+	ConditionDefaultConflict = "DefaultConflict"
+
+	// ConditionNameConflict indicates an older PortalExpose publishes the same app name on a shared relay domain
+	ConditionNameConflict = "NameConflict"
 )
 
 // SetCondition updates or adds a condition to the condition list
 // Only updates lastTransitionTime if the status actually changed
-func SetCondition(conditions *[]metav1.Condition, conditionType string, status metav1.ConditionStatus, reason, message string) {
+// generation is the spec generation the condition was computed from
+func SetCondition(conditions *[]metav1.Condition, generation int64, conditionType string, status metav1.ConditionStatus, reason, message string) {
 	now := metav1.NewTime(time.Now())
 
 	// Find existing condition
@@ -63,7 +95,7 @@ func SetCondition(conditions *[]metav1.Condition, conditionType string, status m
 			(*conditions)[i].Status = status
 			(*conditions)[i].Reason = reason
 			(*conditions)[i].Message = message
-			(*conditions)[i].ObservedGeneration = 0 // Will be set by caller if needed
+			(*conditions)[i].ObservedGeneration = generation
 			return
 		}
 	}
@@ -75,7 +107,7 @@ func SetCondition(conditions *[]metav1.Condition, conditionType string, status m
 		LastTransitionTime: now,
 		Reason:             reason,
 		Message:            message,
-		ObservedGeneration: 0,
+		ObservedGeneration: generation,
 	})
 }
 
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetCondition(t *testing.T) {
+	var conditions []metav1.Condition
+
+	SetCondition(&conditions, 1, ConditionAvailable, metav1.ConditionFalse, "Pending", "Waiting for pods")
+	if len(conditions) != 1 || conditions[0].ObservedGeneration != 1 {
+		t.Fatalf("SetCondition() = %+v, want one condition at generation 1", conditions)
+	}
+	transition := conditions[0].LastTransitionTime
+
+	// Same status at a newer generation keeps the transition time
+	SetCondition(&conditions, 2, ConditionAvailable, metav1.ConditionFalse, "Pending", "Still waiting")
+	if conditions[0].ObservedGeneration != 2 {
+		t.Errorf("ObservedGeneration = %d, want 2", conditions[0].ObservedGeneration)
+	}
+	if !conditions[0].LastTransitionTime.Equal(&transition) {
+		t.Errorf("LastTransitionTime changed without a status change")
+	}
+	if conditions[0].Message != "Still waiting" {
+		t.Errorf("Message = %q, want %q", conditions[0].Message, "Still waiting")
+	}
+
+	SetCondition(&conditions, 2, ConditionProgressing, metav1.ConditionTrue, "DeploymentCreated", "Created")
+	if len(conditions) != 2 || conditions[1].ObservedGeneration != 2 {
+		t.Errorf("SetCondition() = %+v, want a second condition at generation 2", conditions)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Event reasons recorded by the controllers
+//
+// Transition events are recorded only when a value actually changes: a PortalExpose phase change
+// uses the new phase as reason (Pending, Ready, Degraded or Failed) and a condition status change
+// uses the condition type. The reasons below mark one-off actions.
+const (
+	// EventReasonCreated is recorded when the tunnel Deployment of a PortalExpose is created
+	EventReasonCreated = "Created"
+
+	// EventReasonDeleted is recorded when the tunnel of a deleted PortalExpose is cleaned up
+	EventReasonDeleted = "Deleted"
+
+	// EventReasonIdentityRotated is recorded when the tunnel identity keypair is regenerated
+	EventReasonIdentityRotated = "IdentityRotated"
+
+	// EventReasonSecurityOverride is recorded on a TunnelClass when its active security override changes
+	EventReasonSecurityOverride = "SecurityOverride"
+
+	// EventReasonExposed is recorded on a Service or Ingress when its PortalExpose is generated
+	EventReasonExposed = "Exposed"
+
+	// EventReasonUnexposed is recorded on a Service or Ingress when its generated PortalExpose is deleted
+	EventReasonUnexposed = "Unexposed"
+
+	// EventReasonInvalidAnnotations is recorded on a Service whose exposure annotations cannot be translated
+	EventReasonInvalidAnnotations = "InvalidAnnotations"
+
+	// EventReasonInvalidIngress is recorded on an Ingress that cannot be translated
+	EventReasonInvalidIngress = "InvalidIngress"
+
+	// EventReasonPortalExposeExists is recorded on a Service or Ingress whose PortalExpose name is taken
+	// by a PortalExpose it does not manage
+	EventReasonPortalExposeExists = "PortalExposeExists"
+)
+
+// Transition is a change of the phase or of a condition status worth an event
+type Transition struct {
+	// Reason is the event reason: the new phase or the condition type
+	Reason string
+
+	// Warning is set when the new value is unhealthy
+	Warning bool
+
+	// Message carries the old and the new value
+	Message string
+}
+
+// PhaseTransition returns the transition between two phases
+// It returns false when the phase did not change
+func PhaseTransition(previous, current, detail string) (Transition, bool) {
+	if current == "" || previous == current {
+		return Transition{}, false
+	}
+
+	message := fmt.Sprintf("Phase changed from %s to %s", valueOrNone(previous), current)
+	if detail != "" {
+		message += ": " + detail
+	}
+	return Transition{
+		Reason:  current,
+		Warning: current == PhaseDegraded || current == PhaseFailed,
+		Message: message,
+	}, true
+}
+
+// ConditionTransitions returns a transition for every condition whose status changed
+// A condition that first appears is only reported when it is unhealthy
+func ConditionTransitions(previous, current []metav1.Condition) []Transition {
+	var transitions []Transition
+	for _, condition := range current {
+		from := ""
+		if old := FindCondition(previous, condition.Type); old != nil {
+			if old.Status == condition.Status {
+				continue
+			}
+			from = string(old.Status)
+		} else if !conditionUnhealthy(condition) {
+			continue
+		}
+
+		transitions = append(transitions, Transition{
+			Reason:  condition.Type,
+			Warning: conditionUnhealthy(condition),
+			Message: fmt.Sprintf("%s changed from %s to %s: %s",
+				condition.Type, valueOrNone(from), condition.Status, condition.Message),
+		})
+	}
+	return transitions
+}
+
+// conditionUnhealthy reports whether a condition status calls for attention
+// Conditions named after a problem are unhealthy when True, all others when False
+func conditionUnhealthy(condition metav1.Condition) bool {
+	switch condition.Type {
+	case ConditionProgressing:
+		return false
+	case ConditionCredentialsMissing, ConditionNameConflict, ConditionInvalidSize:
+		return condition.Status == metav1.ConditionTrue
+	default:
+		return condition.Status == metav1.ConditionFalse
+	}
+}
+
+// valueOrNone names an unset phase or condition status in event messages
+func valueOrNone(value string) string {
+	if value == "" {
+		return "None"
+	}
+	return value
+}
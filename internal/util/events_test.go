@@ -0,0 +1,113 @@
+package util
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPhaseTransition(t *testing.T) {
+	tests := []struct {
+		name        string
+		previous    string
+		current     string
+		detail      string
+		wantOK      bool
+		wantWarning bool
+		wantMessage string
+	}{
+		{
+			name:     "Unchanged phase",
+			previous: PhaseReady,
+			current:  PhaseReady,
+		},
+		{
+			name:        "First phase",
+			current:     PhasePending,
+			wantOK:      true,
+			wantMessage: "Phase changed from None to Pending",
+		},
+		{
+			name:        "Degraded",
+			previous:    PhaseReady,
+			current:     PhaseDegraded,
+			detail:      "2/2 tunnel pods ready, 1/2 relays connected",
+			wantOK:      true,
+			wantWarning: true,
+			wantMessage: "Phase changed from Ready to Degraded: 2/2 tunnel pods ready, 1/2 relays connected",
+		},
+		{
+			name:        "Recovered",
+			previous:    PhaseFailed,
+			current:     PhaseReady,
+			wantOK:      true,
+			wantMessage: "Phase changed from Failed to Ready",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transition, ok := PhaseTransition(tt.previous, tt.current, tt.detail)
+			if ok != tt.wantOK {
+				t.Fatalf("PhaseTransition() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if transition.Reason != tt.current {
+				t.Errorf("Reason = %q, want %q", transition.Reason, tt.current)
+			}
+			if transition.Warning != tt.wantWarning {
+				t.Errorf("Warning = %v, want %v", transition.Warning, tt.wantWarning)
+			}
+			if transition.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", transition.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestConditionTransitions(t *testing.T) {
+	condition := func(conditionType string, status metav1.ConditionStatus) metav1.Condition {
+		return metav1.Condition{Type: conditionType, Status: status, Message: conditionType + " message"}
+	}
+
+	previous := []metav1.Condition{
+		condition(ConditionRelayConnected, metav1.ConditionTrue),
+		condition(ConditionServiceExists, metav1.ConditionTrue),
+		condition(ConditionProgressing, metav1.ConditionTrue),
+	}
+	current := []metav1.Condition{
+		condition(ConditionRelayConnected, metav1.ConditionFalse),    // changed, unhealthy
+		condition(ConditionServiceExists, metav1.ConditionTrue),      // unchanged
+		condition(ConditionProgressing, metav1.ConditionFalse),       // changed, never unhealthy
+		condition(ConditionNameConflict, metav1.ConditionFalse),      // new and healthy
+		condition(ConditionCredentialsMissing, metav1.ConditionTrue), // new and unhealthy
+	}
+
+	transitions := ConditionTransitions(previous, current)
+	want := []Transition{
+		{
+			Reason:  ConditionRelayConnected,
+			Warning: true,
+			Message: "RelayConnected changed from True to False: RelayConnected message",
+		},
+		{
+			Reason:  ConditionProgressing,
+			Message: "Progressing changed from True to False: Progressing message",
+		},
+		{
+			Reason:  ConditionCredentialsMissing,
+			Warning: true,
+			Message: "CredentialsMissing changed from None to True: CredentialsMissing message",
+		},
+	}
+	if len(transitions) != len(want) {
+		t.Fatalf("ConditionTransitions() = %+v, want %+v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("transition %d = %+v, want %+v", i, transitions[i], want[i])
+		}
+	}
+}
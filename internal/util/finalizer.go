@@ -24,6 +24,9 @@ import (
 const (
 	// FinalizerName is the finalizer added to PortalExpose resources
 	FinalizerName = "portal.gosuda.org/cleanup-tunnel-deployment"
+
+	// TunnelClassFinalizerName is the finalizer keeping a TunnelClass while PortalExposes use it
+	TunnelClassFinalizerName = "portal.gosuda.org/tunnelclass-in-use"
 )
 
 // AddFinalizer adds a finalizer to the object if it doesn't already exist
@@ -0,0 +1,210 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/tunnel"
+	"github.com/gosuda/portal-expose/internal/tunnelclass"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var portalexposelog = logf.Log.WithName("portalexpose-resource")
+
+// SetupPortalExposeWebhookWithManager registers the webhook for PortalExpose in the manager.
+func SetupPortalExposeWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&portalv1alpha1.PortalExpose{}).
+		WithValidator(&PortalExposeCustomValidator{Client: mgr.GetClient(), APIReader: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-portal-gosuda-org-v1alpha1-portalexpose,mutating=false,failurePolicy=fail,sideEffects=None,groups=portal.gosuda.org,resources=portalexposes,verbs=create;update,versions=v1alpha1,name=vportalexpose-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// PortalExposeCustomValidator rejects PortalExpose specs that would otherwise only
+// fail after reconciliation, and warns about risky but allowed specs.
+type PortalExposeCustomValidator struct {
+	Client client.Client
+
+	// APIReader reads Secrets, which the manager cache only holds once the controller has labeled them
+	// Defaults to the Client when nil
+	APIReader client.Reader
+}
+
+var _ webhook.CustomValidator = &PortalExposeCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type PortalExpose.
+func (v *PortalExposeCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	portalexpose, ok := obj.(*portalv1alpha1.PortalExpose)
+	if !ok {
+		return nil, fmt.Errorf("expected a PortalExpose object but got %T", obj)
+	}
+	portalexposelog.Info("Validation for PortalExpose upon creation", "name", portalexpose.GetName())
+
+	return v.validatePortalExpose(ctx, portalexpose)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type PortalExpose.
+func (v *PortalExposeCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldPortalExpose, ok := oldObj.(*portalv1alpha1.PortalExpose)
+	if !ok {
+		return nil, fmt.Errorf("expected a PortalExpose object for the oldObj but got %T", oldObj)
+	}
+	portalexpose, ok := newObj.(*portalv1alpha1.PortalExpose)
+	if !ok {
+		return nil, fmt.Errorf("expected a PortalExpose object for the newObj but got %T", newObj)
+	}
+	portalexposelog.Info("Validation for PortalExpose upon update", "name", portalexpose.GetName())
+
+	// Metadata-only updates (e.g. finalizers) must not be blocked by cluster state that changed since creation
+	if equality.Semantic.DeepEqual(oldPortalExpose.Spec, portalexpose.Spec) {
+		return nil, nil
+	}
+
+	return v.validatePortalExpose(ctx, portalexpose)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type PortalExpose.
+func (v *PortalExposeCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// apiReader returns the reader for objects the manager cache does not hold
+func (v *PortalExposeCustomValidator) apiReader() client.Reader {
+	if v.APIReader != nil {
+		return v.APIReader
+	}
+	return v.Client
+}
+
+// validatePortalExpose checks the spec against the cluster state
+func (v *PortalExposeCustomValidator) validatePortalExpose(
+	ctx context.Context,
+	portalexpose *portalv1alpha1.PortalExpose,
+) (admission.Warnings, error) {
+	var allErrs field.ErrorList
+	var warnings admission.Warnings
+	specPath := field.NewPath("spec")
+
+	// Relay target names must be unique, they key status.relay.connected
+	targetsPath := specPath.Child("relay", "targets")
+	seen := make(map[string]bool, len(portalexpose.Spec.Relay.Targets))
+	for i, target := range portalexpose.Spec.Relay.Targets {
+		if seen[target.Name] {
+			allErrs = append(allErrs, field.Duplicate(targetsPath.Index(i).Child("name"), target.Name))
+		}
+		seen[target.Name] = true
+
+		if target.RelayRef != nil {
+			clusterRelay := &portalv1alpha1.ClusterRelay{}
+			if err := v.Client.Get(ctx, client.ObjectKey{Name: target.RelayRef.Name}, clusterRelay); err != nil {
+				if !apierrors.IsNotFound(err) {
+					return nil, err
+				}
+				warnings = append(warnings, fmt.Sprintf("ClusterRelay %q does not exist yet; the PortalExpose stays Failed until it is created",
+					target.RelayRef.Name))
+			}
+		}
+
+		if ref := target.CredentialsSecretRef; ref != nil {
+			secret := &corev1.Secret{}
+			if err := v.apiReader().Get(ctx, client.ObjectKey{Namespace: portalexpose.Namespace, Name: ref.Name}, secret); err != nil {
+				if !apierrors.IsNotFound(err) {
+					return nil, err
+				}
+				warnings = append(warnings, fmt.Sprintf("Secret %q does not exist yet; the PortalExpose stays Failed until it is created",
+					ref.Name))
+			}
+		}
+	}
+	for _, i := range tunnel.CredentialsEnvPrefixConflicts(portalexpose.Spec.Relay.Targets) {
+		name := portalexpose.Spec.Relay.Targets[i].Name
+		allErrs = append(allErrs, field.Invalid(targetsPath.Index(i).Child("name"), name,
+			fmt.Sprintf("credentials variable prefix %s overlaps another target mounting credentials as Env", tunnel.CredentialsEnvPrefix(name))))
+	}
+	if len(portalexpose.Spec.Relay.Targets) == 1 {
+		warnings = append(warnings, "only one relay target is configured; the public URL goes down with that relay")
+	}
+
+	// Every endpoint needs its own subdomain and a port that exists on its Service
+	subdomains := make(map[string]bool, 1+len(portalexpose.Spec.Endpoints))
+	subdomains[portalexpose.Spec.App.Name] = true
+	for i, endpoint := range portalexpose.Spec.Endpoints {
+		if subdomains[endpoint.Subdomain] {
+			allErrs = append(allErrs, field.Duplicate(specPath.Child("endpoints").Index(i).Child("subdomain"), endpoint.Subdomain))
+		}
+		subdomains[endpoint.Subdomain] = true
+	}
+	if n := len(portalexpose.Spec.Endpoints); n > 0 {
+		warnings = append(warnings, fmt.Sprintf("each of the %d tunnel containers requests the full TunnelClass resources; "+
+			"the tunnel pods request %d times the class size", n+1, n+1))
+	}
+
+	servicePaths := []*field.Path{specPath.Child("app", "service")}
+	for i := range portalexpose.Spec.Endpoints {
+		servicePaths = append(servicePaths, specPath.Child("endpoints").Index(i).Child("service"))
+	}
+	for i, endpoint := range tunnel.AppEndpoints(portalexpose) {
+		service := &corev1.Service{}
+		serviceKey := client.ObjectKey{Namespace: portalexpose.Namespace, Name: endpoint.Service.Name}
+		if err := v.Client.Get(ctx, serviceKey, service); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			warnings = append(warnings, fmt.Sprintf("Service %q does not exist yet; the PortalExpose stays Failed until it is created",
+				endpoint.Service.Name))
+		} else if _, err := tunnel.ResolveServicePort(service, endpoint.Service.Port); err != nil {
+			allErrs = append(allErrs, field.NotFound(servicePaths[i].Child("port"), endpoint.Service.Port.String()))
+		}
+	}
+
+	// The TunnelClass must exist and allow the namespace when named explicitly
+	if _, err := tunnelclass.GetTunnelClass(ctx, v.Client, portalexpose.Namespace, portalexpose.Spec.TunnelClassName); err != nil {
+		name := portalexpose.Spec.TunnelClassName
+		switch {
+		case name == "":
+			warnings = append(warnings, fmt.Sprintf("no tunnelClassName set and %s", err.Error()))
+		case apierrors.IsNotFound(err):
+			allErrs = append(allErrs, field.NotFound(specPath.Child("tunnelClassName"), name))
+		case errors.Is(err, tunnelclass.ErrNamespaceNotAllowed):
+			allErrs = append(allErrs, field.Forbidden(specPath.Child("tunnelClassName"), err.Error()))
+		default:
+			return nil, err
+		}
+	}
+
+	if len(allErrs) > 0 {
+		return warnings, apierrors.NewInvalid(
+			portalv1alpha1.GroupVersion.WithKind("PortalExpose").GroupKind(), portalexpose.Name, allErrs)
+	}
+	return warnings, nil
+}
@@ -0,0 +1,258 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add client-go scheme: %v", err)
+	}
+	if err := portalv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add portal scheme: %v", err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func testPortalExpose(mutate func(*portalv1alpha1.PortalExpose)) *portalv1alpha1.PortalExpose {
+	portalExpose := &portalv1alpha1.PortalExpose{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-app", Namespace: "default"},
+		Spec: portalv1alpha1.PortalExposeSpec{
+			TunnelClassName: "standard",
+			App: portalv1alpha1.AppSpec{
+				Name:    "my-app",
+				Service: portalv1alpha1.ServiceRef{Name: "my-svc", Port: intstr.FromInt32(8080)},
+			},
+			Relay: portalv1alpha1.RelaySpec{
+				Targets: []portalv1alpha1.RelayTarget{
+					{Name: "primary", URL: "wss://portal.gosuda.org/relay"},
+					{Name: "secondary", URL: "wss://portal.thumbgo.kr/relay"},
+				},
+			},
+		},
+	}
+	if mutate != nil {
+		mutate(portalExpose)
+	}
+	return portalExpose
+}
+
+func TestPortalExposeValidateCreate(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-svc", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 8080}}},
+	}
+	tunnelClass := &portalv1alpha1.TunnelClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "standard"},
+		Spec:       portalv1alpha1.TunnelClassSpec{Replicas: 2, Size: "small"},
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+	tenantClass := &portalv1alpha1.TunnelClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "tenant"},
+		Spec: portalv1alpha1.TunnelClassSpec{
+			Replicas:          2,
+			Size:              "small",
+			AllowedNamespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		objs         []client.Object
+		portalExpose *portalv1alpha1.PortalExpose
+		wantErr      bool
+		wantWarnings int
+	}{
+		{
+			name:         "Valid spec",
+			objs:         []client.Object{namespace, service, tunnelClass},
+			portalExpose: testPortalExpose(nil),
+		},
+		{
+			name: "Port not in Service",
+			objs: []client.Object{namespace, service, tunnelClass},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.App.Service.Port = intstr.FromInt32(9090)
+			}),
+			wantErr: true,
+		},
+		{
+			name: "Endpoint subdomain reuses the app name",
+			objs: []client.Object{namespace, service, tunnelClass},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.Endpoints = []portalv1alpha1.EndpointSpec{
+					{Name: "admin", Subdomain: pe.Spec.App.Name, Service: pe.Spec.App.Service},
+				}
+			}),
+			wantErr:      true,
+			wantWarnings: 1,
+		},
+		{
+			name: "Endpoint port not in Service",
+			objs: []client.Object{namespace, service, tunnelClass},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.Endpoints = []portalv1alpha1.EndpointSpec{
+					{Name: "admin", Subdomain: "my-app-admin", Service: portalv1alpha1.ServiceRef{Name: "my-svc", Port: intstr.FromString("admin")}},
+				}
+			}),
+			wantErr:      true,
+			wantWarnings: 1,
+		},
+		{
+			name: "Endpoints are allowed with a resources warning",
+			objs: []client.Object{namespace, service, tunnelClass},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.Endpoints = []portalv1alpha1.EndpointSpec{
+					{Name: "admin", Subdomain: "my-app-admin", Service: pe.Spec.App.Service},
+				}
+			}),
+			wantWarnings: 1,
+		},
+		{
+			name: "Named port",
+			objs: []client.Object{namespace, service, tunnelClass},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.App.Service.Port = intstr.FromString("http")
+			}),
+		},
+		{
+			name: "Duplicate relay target names",
+			objs: []client.Object{namespace, service, tunnelClass},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.Relay.Targets[1].Name = "primary"
+			}),
+			wantErr: true,
+		},
+		{
+			name: "Unknown TunnelClass",
+			objs: []client.Object{namespace, service},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.TunnelClassName = "missing"
+			}),
+			wantErr: true,
+		},
+		{
+			name:         "Missing Service is allowed with a warning",
+			objs:         []client.Object{namespace, tunnelClass},
+			portalExpose: testPortalExpose(nil),
+			wantWarnings: 1,
+		},
+		{
+			name: "Relay credentials Secret exists",
+			objs: []client.Object{namespace, service, tunnelClass, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "relay-token", Namespace: "default"},
+			}},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.Relay.Targets[0].CredentialsSecretRef = &portalv1alpha1.RelayCredentialsReference{Name: "relay-token"}
+			}),
+		},
+		{
+			name: "Missing relay credentials Secret is allowed with a warning",
+			objs: []client.Object{namespace, service, tunnelClass},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.Relay.Targets[0].CredentialsSecretRef = &portalv1alpha1.RelayCredentialsReference{Name: "relay-token"}
+			}),
+			wantWarnings: 1,
+		},
+		{
+			name: "Relay targets sharing a credentials variable prefix",
+			objs: []client.Object{namespace, service, tunnelClass, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "relay-token", Namespace: "default"},
+			}},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.Relay.Targets[0].Name = "relay-a"
+				pe.Spec.Relay.Targets[1].Name = "relay.a"
+				for i := range pe.Spec.Relay.Targets {
+					pe.Spec.Relay.Targets[i].CredentialsSecretRef = &portalv1alpha1.RelayCredentialsReference{
+						Name: "relay-token", MountAs: portalv1alpha1.RelayCredentialsMountEnv,
+					}
+				}
+			}),
+			wantErr: true,
+		},
+		{
+			name: "Single relay is allowed with a warning",
+			objs: []client.Object{namespace, service, tunnelClass},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.Relay.Targets = pe.Spec.Relay.Targets[:1]
+			}),
+			wantWarnings: 1,
+		},
+		{
+			name: "TunnelClass does not allow the namespace",
+			objs: []client.Object{namespace, service, tenantClass},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.TunnelClassName = "tenant"
+			}),
+			wantErr: true,
+		},
+		{
+			name: "TunnelClass allows the labeled namespace",
+			objs: []client.Object{service, tenantClass, &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"tenant": "a"}},
+			}},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.TunnelClassName = "tenant"
+			}),
+		},
+		{
+			name: "Namespace picks the default class",
+			objs: []client.Object{service, tunnelClass, &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "default",
+					Annotations: map[string]string{"portal.gosuda.org/default-tunnel-class": "standard"},
+				},
+			}},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.TunnelClassName = ""
+			}),
+		},
+		{
+			name: "No default class is allowed with a warning",
+			objs: []client.Object{namespace, service, tunnelClass},
+			portalExpose: testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+				pe.Spec.TunnelClassName = ""
+			}),
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &PortalExposeCustomValidator{Client: newFakeClient(t, tt.objs...)}
+			warnings, err := validator.ValidateCreate(context.Background(), tt.portalExpose)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("ValidateCreate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestPortalExposeValidateUpdateMetadataOnly(t *testing.T) {
+	oldObj := testPortalExpose(func(pe *portalv1alpha1.PortalExpose) {
+		pe.Spec.App.Service.Port = intstr.FromInt32(9090)
+	})
+	newObj := oldObj.DeepCopy()
+	newObj.Finalizers = []string{"portal.gosuda.org/cleanup-tunnel-deployment"}
+
+	validator := &PortalExposeCustomValidator{Client: newFakeClient(t)}
+	if _, err := validator.ValidateUpdate(context.Background(), oldObj, newObj); err != nil {
+		t.Errorf("ValidateUpdate() error = %v, want metadata-only update allowed", err)
+	}
+}
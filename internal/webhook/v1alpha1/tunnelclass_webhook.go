@@ -0,0 +1,170 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/tunnel"
+	"github.com/gosuda/portal-expose/internal/tunnelclass"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var tunnelclasslog = logf.Log.WithName("tunnelclass-resource")
+
+// SetupTunnelClassWebhookWithManager registers the webhook for TunnelClass in the manager.
+func SetupTunnelClassWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&portalv1alpha1.TunnelClass{}).
+		WithValidator(&TunnelClassCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-portal-gosuda-org-v1alpha1-tunnelclass,mutating=false,failurePolicy=fail,sideEffects=None,groups=portal.gosuda.org,resources=tunnelclasses,verbs=create;update,versions=v1alpha1,name=vtunnelclass-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// TunnelClassCustomValidator rejects a second default TunnelClass, pod template overlays
+// touching controller-owned keys and unexplained security overrides, and warns about
+// risky but allowed specs.
+type TunnelClassCustomValidator struct {
+	Client client.Client
+}
+
+var _ webhook.CustomValidator = &TunnelClassCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type TunnelClass.
+func (v *TunnelClassCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	tunnelClass, ok := obj.(*portalv1alpha1.TunnelClass)
+	if !ok {
+		return nil, fmt.Errorf("expected a TunnelClass object but got %T", obj)
+	}
+	tunnelclasslog.Info("Validation for TunnelClass upon creation", "name", tunnelClass.GetName())
+
+	return v.validateTunnelClass(ctx, tunnelClass)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type TunnelClass.
+func (v *TunnelClassCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldTunnelClass, ok := oldObj.(*portalv1alpha1.TunnelClass)
+	if !ok {
+		return nil, fmt.Errorf("expected a TunnelClass object for the oldObj but got %T", oldObj)
+	}
+	tunnelClass, ok := newObj.(*portalv1alpha1.TunnelClass)
+	if !ok {
+		return nil, fmt.Errorf("expected a TunnelClass object for the newObj but got %T", newObj)
+	}
+	tunnelclasslog.Info("Validation for TunnelClass upon update", "name", tunnelClass.GetName())
+
+	// Updates that change neither the spec nor the default marker or override reason (e.g. finalizers) are always allowed
+	if equality.Semantic.DeepEqual(oldTunnelClass.Spec, tunnelClass.Spec) &&
+		tunnelclass.IsDefault(oldTunnelClass) == tunnelclass.IsDefault(tunnelClass) &&
+		oldTunnelClass.Annotations[tunnel.SecurityOverrideReasonAnnotation] == tunnelClass.Annotations[tunnel.SecurityOverrideReasonAnnotation] {
+		return nil, nil
+	}
+
+	return v.validateTunnelClass(ctx, tunnelClass)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type TunnelClass.
+func (v *TunnelClassCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateTunnelClass checks the spec against the other TunnelClasses
+func (v *TunnelClassCustomValidator) validateTunnelClass(
+	ctx context.Context,
+	tunnelClass *portalv1alpha1.TunnelClass,
+) (admission.Warnings, error) {
+	var allErrs field.ErrorList
+	var warnings admission.Warnings
+
+	// Only one TunnelClass may be marked default
+	if tunnelclass.IsDefault(tunnelClass) {
+		tunnelClasses := &portalv1alpha1.TunnelClassList{}
+		if err := v.Client.List(ctx, tunnelClasses); err != nil {
+			return nil, err
+		}
+		for i := range tunnelClasses.Items {
+			other := &tunnelClasses.Items[i]
+			if other.Name == tunnelClass.Name || !tunnelclass.IsDefault(other) {
+				continue
+			}
+			allErrs = append(allErrs, field.Forbidden(
+				field.NewPath("metadata", "annotations").Key(tunnelclass.DefaultClassAnnotation),
+				fmt.Sprintf("TunnelClass %q is already the default; remove its annotation first", other.Name)))
+			break
+		}
+	}
+
+	allErrs = append(allErrs, tunnelclass.ValidateSpec(tunnelClass)...)
+
+	if override := tunnel.ActiveSecurityOverride(tunnelClass); override != nil && tunnel.ViolatesRestricted(override) {
+		warnings = append(warnings, fmt.Sprintf("securityOverride relaxes %s; tunnel pods are rejected in namespaces enforcing the restricted Pod Security Standard",
+			strings.Join(tunnel.RelaxedSettings(override), ", ")))
+	}
+
+	// Autoscaled classes may run as few as minReplicas pods
+	replicas := tunnelClass.Spec.Replicas
+	if autoscaling := tunnelClass.Spec.Autoscaling; autoscaling != nil {
+		replicas = autoscaling.MinReplicas
+		targetsCPU := autoscaling.TargetCPUUtilizationPercentage != nil || autoscaling.TargetConnectionsPerPod == nil
+		if targetsCPU && tunnelClass.Spec.Resources != nil &&
+			tunnelClass.Spec.Resources.Requests.Cpu().IsZero() {
+			warnings = append(warnings, "autoscaling targets CPU utilization but resources set no CPU request; the HorizontalPodAutoscaler cannot scale")
+		}
+	}
+
+	if budget := tunnelClass.Spec.DisruptionBudget; budget != nil && blocksEviction(budget, replicas) {
+		warnings = append(warnings, "disruptionBudget allows no voluntary evictions; node drains block on tunnel pods of this class")
+	}
+
+	if replicas == 1 {
+		warnings = append(warnings, "replicas is 1; exposures using this class go down whenever the tunnel pod restarts")
+	}
+
+	if len(allErrs) > 0 {
+		return warnings, apierrors.NewInvalid(
+			portalv1alpha1.GroupVersion.WithKind("TunnelClass").GroupKind(), tunnelClass.Name, allErrs)
+	}
+	return warnings, nil
+}
+
+// blocksEviction reports whether the disruption budget never lets a tunnel pod be evicted
+func blocksEviction(budget *portalv1alpha1.TunnelDisruptionBudget, replicas int32) bool {
+	if budget.MaxUnavailable != nil {
+		maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(budget.MaxUnavailable, int(replicas), true)
+		return err == nil && maxUnavailable == 0
+	}
+	if budget.MinAvailable != nil {
+		minAvailable, err := intstr.GetScaledValueFromIntOrPercent(budget.MinAvailable, int(replicas), true)
+		return err == nil && minAvailable >= int(replicas)
+	}
+	return false
+}
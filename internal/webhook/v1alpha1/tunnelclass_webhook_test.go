@@ -0,0 +1,189 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+func testTunnelClass(name string, isDefault bool, replicas int32) *portalv1alpha1.TunnelClass {
+	tunnelClass := &portalv1alpha1.TunnelClass{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       portalv1alpha1.TunnelClassSpec{Replicas: replicas, Size: "small"},
+	}
+	if isDefault {
+		tunnelClass.Annotations = map[string]string{"portal.gosuda.org/is-default-class": "true"}
+	}
+	return tunnelClass
+}
+
+func withPodTemplate(tunnelClass *portalv1alpha1.TunnelClass, labels, annotations map[string]string) *portalv1alpha1.TunnelClass {
+	tunnelClass.Spec.PodTemplate = &portalv1alpha1.TunnelPodTemplate{
+		Metadata: portalv1alpha1.TunnelPodMetadata{Labels: labels, Annotations: annotations},
+	}
+	return tunnelClass
+}
+
+func withSecurityOverride(tunnelClass *portalv1alpha1.TunnelClass, reason string) *portalv1alpha1.TunnelClass {
+	runAsNonRoot := false
+	tunnelClass.Spec.SecurityOverride = &portalv1alpha1.TunnelSecurityOverride{RunAsNonRoot: &runAsNonRoot}
+	if reason != "" {
+		tunnelClass.Annotations = map[string]string{"portal.gosuda.org/security-override-reason": reason}
+	}
+	return tunnelClass
+}
+
+func withDisruptionBudget(tunnelClass *portalv1alpha1.TunnelClass, minAvailable, maxUnavailable *intstr.IntOrString) *portalv1alpha1.TunnelClass {
+	tunnelClass.Spec.DisruptionBudget = &portalv1alpha1.TunnelDisruptionBudget{
+		MinAvailable:   minAvailable,
+		MaxUnavailable: maxUnavailable,
+	}
+	return tunnelClass
+}
+
+func TestTunnelClassValidateCreate(t *testing.T) {
+	tests := []struct {
+		name         string
+		objs         []client.Object
+		tunnelClass  *portalv1alpha1.TunnelClass
+		wantErr      bool
+		wantWarnings int
+	}{
+		{
+			name:        "First default",
+			tunnelClass: testTunnelClass("default", true, 2),
+		},
+		{
+			name:        "Second default is rejected",
+			objs:        []client.Object{testTunnelClass("default", true, 2)},
+			tunnelClass: testTunnelClass("other", true, 2),
+			wantErr:     true,
+		},
+		{
+			name:        "Non-default next to a default",
+			objs:        []client.Object{testTunnelClass("default", true, 2)},
+			tunnelClass: testTunnelClass("other", false, 2),
+		},
+		{
+			name: "Pod template with custom labels",
+			tunnelClass: withPodTemplate(testTunnelClass("custom", false, 2), map[string]string{
+				"team": "platform",
+			}, map[string]string{"prometheus.io/scrape": "true"}),
+		},
+		{
+			name: "Pod template overriding a selector label is rejected",
+			tunnelClass: withPodTemplate(testTunnelClass("custom", false, 2), map[string]string{
+				"app.kubernetes.io/name": "other",
+			}, nil),
+			wantErr: true,
+		},
+		{
+			name: "Pod template with a reserved annotation is rejected",
+			tunnelClass: withPodTemplate(testTunnelClass("custom", false, 2), nil, map[string]string{
+				"portal.gosuda.org/config-hash": "abc",
+			}),
+			wantErr: true,
+		},
+		{
+			name:        "Security override without reason is rejected",
+			tunnelClass: withSecurityOverride(testTunnelClass("privileged", false, 2), ""),
+			wantErr:     true,
+		},
+		{
+			name:         "Security override with reason is allowed with a warning",
+			tunnelClass:  withSecurityOverride(testTunnelClass("privileged", false, 2), "legacy image runs as root"),
+			wantWarnings: 1,
+		},
+		{
+			name: "Disruption budget allowing evictions",
+			tunnelClass: withDisruptionBudget(testTunnelClass("ha", false, 3),
+				&intstr.IntOrString{Type: intstr.Int, IntVal: 2}, nil),
+		},
+		{
+			name: "Disruption budget blocking evictions is allowed with a warning",
+			tunnelClass: withDisruptionBudget(testTunnelClass("ha", false, 3),
+				&intstr.IntOrString{Type: intstr.String, StrVal: "100%"}, nil),
+			wantWarnings: 1,
+		},
+		{
+			name: "Disruption budget with no unavailable pods is allowed with a warning",
+			tunnelClass: withDisruptionBudget(testTunnelClass("ha", false, 3),
+				nil, &intstr.IntOrString{Type: intstr.Int, IntVal: 0}),
+			wantWarnings: 1,
+		},
+		{
+			name: "Autoscaling with a size tier",
+			tunnelClass: func() *portalv1alpha1.TunnelClass {
+				tunnelClass := testTunnelClass("autoscaled", false, 2)
+				tunnelClass.Spec.Autoscaling = &portalv1alpha1.TunnelAutoscaling{MinReplicas: 2, MaxReplicas: 10}
+				return tunnelClass
+			}(),
+		},
+		{
+			name: "Autoscaling on CPU without a CPU request is allowed with a warning",
+			tunnelClass: func() *portalv1alpha1.TunnelClass {
+				tunnelClass := testTunnelClass("autoscaled", false, 2)
+				tunnelClass.Spec.Size = ""
+				tunnelClass.Spec.Resources = &corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+				}
+				tunnelClass.Spec.Autoscaling = &portalv1alpha1.TunnelAutoscaling{MinReplicas: 2, MaxReplicas: 10}
+				return tunnelClass
+			}(),
+			wantWarnings: 1,
+		},
+		{
+			name: "Network policy with relay CIDRs",
+			tunnelClass: func() *portalv1alpha1.TunnelClass {
+				tunnelClass := testTunnelClass("isolated", false, 2)
+				tunnelClass.Spec.NetworkPolicy = &portalv1alpha1.TunnelNetworkPolicy{Enabled: true, RelayCIDRs: []string{"203.0.113.0/24"}}
+				return tunnelClass
+			}(),
+		},
+		{
+			name: "Network policy with an invalid relay CIDR is rejected",
+			tunnelClass: func() *portalv1alpha1.TunnelClass {
+				tunnelClass := testTunnelClass("isolated", false, 2)
+				tunnelClass.Spec.NetworkPolicy = &portalv1alpha1.TunnelNetworkPolicy{Enabled: true, RelayCIDRs: []string{"relay.example.com"}}
+				return tunnelClass
+			}(),
+			wantErr: true,
+		},
+		{
+			name:         "Single replica is allowed with a warning",
+			tunnelClass:  testTunnelClass("dev", false, 1),
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &TunnelClassCustomValidator{Client: newFakeClient(t, tt.objs...)}
+			warnings, err := validator.ValidateCreate(context.Background(), tt.tunnelClass)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("ValidateCreate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestTunnelClassValidateUpdateSelf(t *testing.T) {
+	existing := testTunnelClass("default", true, 2)
+	updated := existing.DeepCopy()
+	updated.Spec.Replicas = 3
+
+	validator := &TunnelClassCustomValidator{Client: newFakeClient(t, existing)}
+	if _, err := validator.ValidateUpdate(context.Background(), existing, updated); err != nil {
+		t.Errorf("ValidateUpdate() error = %v, want update of the default itself allowed", err)
+	}
+}
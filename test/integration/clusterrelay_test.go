@@ -0,0 +1,131 @@
+package integration
+
+import (
+	"slices"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+)
+
+var _ = Describe("ClusterRelay Controller", func() {
+	const (
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	Context("When a PortalExpose references a ClusterRelay", func() {
+		It("Should roll relay changes out to the tunnel Deployment and count exposures", func() {
+			namespace := "default"
+
+			By("Creating a Service and default TunnelClass")
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "relay-ref-service", Namespace: namespace},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Port: 80}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, service)).Should(Succeed())
+
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "relay-ref-class",
+					Annotations: map[string]string{"portal.gosuda.org/is-default-class": "true"},
+				},
+				Spec: portalv1alpha1.TunnelClassSpec{Replicas: 1, Size: "small"},
+			}
+			Expect(k8sClient.Create(ctx, tunnelClass)).Should(Succeed())
+
+			By("Creating a ClusterRelay")
+			clusterRelay := &portalv1alpha1.ClusterRelay{
+				ObjectMeta: metav1.ObjectMeta{Name: "shared-relay"},
+				Spec: portalv1alpha1.ClusterRelaySpec{
+					URL:          "wss://relay-a.gosuda.org/relay",
+					PublicDomain: "portal.gosuda.org",
+				},
+			}
+			Expect(k8sClient.Create(ctx, clusterRelay)).Should(Succeed())
+
+			By("Creating a PortalExpose referencing the ClusterRelay")
+			portalExpose := &portalv1alpha1.PortalExpose{
+				ObjectMeta: metav1.ObjectMeta{Name: "relay-ref-expose", Namespace: namespace},
+				Spec: portalv1alpha1.PortalExposeSpec{
+					App: portalv1alpha1.AppSpec{
+						Name:    "relay-ref-app",
+						Service: portalv1alpha1.ServiceRef{Name: service.Name, Port: intstr.FromInt32(80)},
+					},
+					Relay: portalv1alpha1.RelaySpec{
+						Targets: []portalv1alpha1.RelayTarget{
+							{Name: "shared", RelayRef: &portalv1alpha1.ClusterRelayReference{Name: clusterRelay.Name}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, portalExpose)).Should(Succeed())
+
+			deploymentKey := types.NamespacedName{Name: portalExpose.Name + "-tunnel", Namespace: namespace}
+			relayArgs := func() []string {
+				deployment := &appsv1.Deployment{}
+				if err := k8sClient.Get(ctx, deploymentKey, deployment); err != nil {
+					return nil
+				}
+				return deployment.Spec.Template.Spec.Containers[0].Args
+			}
+
+			By("Verifying the tunnel uses the ClusterRelay URL")
+			Eventually(func() bool {
+				return slices.Contains(relayArgs(), "wss://relay-a.gosuda.org/relay")
+			}, timeout, interval).Should(BeTrue())
+
+			By("Verifying the public URL uses the ClusterRelay public domain")
+			Eventually(func() string {
+				updated := &portalv1alpha1.PortalExpose{}
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(portalExpose), updated); err != nil {
+					return ""
+				}
+				return updated.Status.PublicURL
+			}, timeout, interval).Should(Equal("https://relay-ref-app.portal.gosuda.org"))
+
+			By("Verifying the ClusterRelay counts the exposure")
+			Eventually(func() int32 {
+				updated := &portalv1alpha1.ClusterRelay{}
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterRelay), updated); err != nil {
+					return -1
+				}
+				return updated.Status.ExposureCount
+			}, timeout, interval).Should(Equal(int32(1)))
+
+			By("Moving the relay")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterRelay), clusterRelay)).Should(Succeed())
+			clusterRelay.Spec.URL = "wss://relay-b.gosuda.org/relay"
+			Expect(k8sClient.Update(ctx, clusterRelay)).Should(Succeed())
+
+			By("Verifying the tunnel Deployment follows the relay")
+			Eventually(func() bool {
+				return slices.Contains(relayArgs(), "wss://relay-b.gosuda.org/relay")
+			}, timeout, interval).Should(BeTrue())
+
+			By("Deleting the PortalExpose")
+			Expect(k8sClient.Delete(ctx, portalExpose)).Should(Succeed())
+			Eventually(func() int32 {
+				updated := &portalv1alpha1.ClusterRelay{}
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterRelay), updated); err != nil {
+					return -1
+				}
+				return updated.Status.ExposureCount
+			}, timeout, interval).Should(Equal(int32(0)))
+
+			Expect(k8sClient.Delete(ctx, clusterRelay)).Should(Succeed())
+			deleteTunnelClass(tunnelClass)
+			Expect(k8sClient.Delete(ctx, service)).Should(Succeed())
+		})
+	})
+})
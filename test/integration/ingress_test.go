@@ -0,0 +1,124 @@
+package integration
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/autoexpose"
+)
+
+var _ = Describe("Ingress support", func() {
+	const (
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	Context("When an Ingress uses the portal IngressClass", func() {
+		It("Should publish it through a PortalExpose and report the hostname", func() {
+			namespace := "default"
+
+			By("Creating the portal IngressClass")
+			ingressClass := &networkingv1.IngressClass{
+				ObjectMeta: metav1.ObjectMeta{Name: "portal"},
+				Spec:       networkingv1.IngressClassSpec{Controller: autoexpose.IngressController},
+			}
+			Expect(k8sClient.Create(ctx, ingressClass)).Should(Succeed())
+
+			By("Creating the backend Service and a TunnelClass")
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "ingress-backend", Namespace: namespace},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Name: "http", Port: 80}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, service)).Should(Succeed())
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{Name: "ingress-tunnel-class"},
+				Spec: portalv1alpha1.TunnelClassSpec{
+					Replicas: 1,
+					Size:     "small",
+				},
+			}
+			Expect(k8sClient.Create(ctx, tunnelClass)).Should(Succeed())
+
+			By("Creating an Ingress of the portal class")
+			className := ingressClass.Name
+			pathType := networkingv1.PathTypePrefix
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "portal-ingress",
+					Namespace: namespace,
+					Annotations: map[string]string{
+						autoexpose.RelayAnnotation:       "wss://portal.gosuda.org/relay",
+						autoexpose.TunnelClassAnnotation: tunnelClass.Name,
+					},
+				},
+				Spec: networkingv1.IngressSpec{
+					IngressClassName: &className,
+					Rules: []networkingv1.IngressRule{{
+						Host: "ingress-app.portal.gosuda.org",
+						IngressRuleValue: networkingv1.IngressRuleValue{
+							HTTP: &networkingv1.HTTPIngressRuleValue{
+								Paths: []networkingv1.HTTPIngressPath{{
+									Path:     "/",
+									PathType: &pathType,
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: service.Name,
+											Port: networkingv1.ServiceBackendPort{Name: "http"},
+										},
+									},
+								}},
+							},
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, ingress)).Should(Succeed())
+
+			By("Verifying the PortalExpose is generated from the Ingress")
+			portalExposeKey := client.ObjectKey{Namespace: namespace, Name: autoexpose.IngressPortalExposeName(ingress)}
+			portalExpose := &portalv1alpha1.PortalExpose{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, portalExposeKey, portalExpose)
+			}, timeout, interval).Should(Succeed())
+			Expect(portalExpose.Spec.App.Name).To(Equal("ingress-app"))
+			Expect(portalExpose.Spec.App.Service.Port).To(Equal(intstr.FromString("http")))
+			Expect(metav1.IsControlledBy(portalExpose, ingress)).To(BeTrue())
+
+			By("Verifying the public hostname is written to the Ingress status")
+			Eventually(func() []networkingv1.IngressLoadBalancerIngress {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(ingress), ingress); err != nil {
+					return nil
+				}
+				return ingress.Status.LoadBalancer.Ingress
+			}, timeout, interval).Should(ConsistOf(
+				networkingv1.IngressLoadBalancerIngress{Hostname: "ingress-app.portal.gosuda.org"}))
+
+			By("Moving the Ingress to another class")
+			otherClass := "other"
+			ingress.Spec.IngressClassName = &otherClass
+			Expect(k8sClient.Update(ctx, ingress)).Should(Succeed())
+
+			By("Verifying the PortalExpose is deleted")
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, portalExposeKey, &portalv1alpha1.PortalExpose{})
+				return errors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(k8sClient.Delete(ctx, ingress)).Should(Succeed())
+			deleteTunnelClass(tunnelClass)
+			Expect(k8sClient.Delete(ctx, service)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, ingressClass)).Should(Succeed())
+		})
+	})
+})
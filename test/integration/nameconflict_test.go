@@ -0,0 +1,115 @@
+package integration
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/util"
+)
+
+var _ = Describe("PortalExpose name conflicts", func() {
+	const (
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	Context("When two PortalExposes publish the same app name on one relay", func() {
+		It("Should fail the newer PortalExpose until the older one is deleted", func() {
+			namespace := "default"
+
+			By("Creating a Service and default TunnelClass")
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "conflict-service", Namespace: namespace},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Port: 80}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, service)).Should(Succeed())
+
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "conflict-class",
+					Annotations: map[string]string{"portal.gosuda.org/is-default-class": "true"},
+				},
+				Spec: portalv1alpha1.TunnelClassSpec{Replicas: 1, Size: "small"},
+			}
+			Expect(k8sClient.Create(ctx, tunnelClass)).Should(Succeed())
+
+			newPortalExpose := func(name string) *portalv1alpha1.PortalExpose {
+				return &portalv1alpha1.PortalExpose{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+					Spec: portalv1alpha1.PortalExposeSpec{
+						App: portalv1alpha1.AppSpec{
+							Name:    "conflict-app",
+							Service: portalv1alpha1.ServiceRef{Name: service.Name, Port: intstr.FromInt32(80)},
+						},
+						Relay: portalv1alpha1.RelaySpec{
+							Targets: []portalv1alpha1.RelayTarget{
+								{Name: "gosuda", URL: "wss://portal.gosuda.org/relay"},
+							},
+						},
+					},
+				}
+			}
+
+			By("Creating the first PortalExpose")
+			first := newPortalExpose("conflict-first")
+			Expect(k8sClient.Create(ctx, first)).Should(Succeed())
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: first.Name + "-tunnel", Namespace: namespace}, &appsv1.Deployment{})
+			}, timeout, interval).Should(Succeed())
+
+			// Creation timestamps have second granularity
+			time.Sleep(time.Second)
+
+			By("Creating a second PortalExpose with the same app name")
+			second := newPortalExpose("conflict-second")
+			Expect(k8sClient.Create(ctx, second)).Should(Succeed())
+
+			By("Verifying the second PortalExpose reports the conflict")
+			Eventually(func() bool {
+				updated := &portalv1alpha1.PortalExpose{}
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(second), updated); err != nil {
+					return false
+				}
+				return updated.Status.Phase == util.PhaseFailed &&
+					meta.IsStatusConditionTrue(updated.Status.Conditions, util.ConditionNameConflict)
+			}, timeout, interval).Should(BeTrue())
+
+			Consistently(func() bool {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: second.Name + "-tunnel", Namespace: namespace}, &appsv1.Deployment{})
+				return errors.IsNotFound(err)
+			}, time.Second*2, interval).Should(BeTrue())
+
+			By("Deleting the first PortalExpose")
+			Expect(k8sClient.Delete(ctx, first)).Should(Succeed())
+
+			By("Verifying the second PortalExpose takes over the name")
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: second.Name + "-tunnel", Namespace: namespace}, &appsv1.Deployment{})
+			}, timeout, interval).Should(Succeed())
+			Eventually(func() bool {
+				updated := &portalv1alpha1.PortalExpose{}
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(second), updated); err != nil {
+					return false
+				}
+				return meta.IsStatusConditionFalse(updated.Status.Conditions, util.ConditionNameConflict)
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(k8sClient.Delete(ctx, second)).Should(Succeed())
+			deleteTunnelClass(tunnelClass)
+			Expect(k8sClient.Delete(ctx, service)).Should(Succeed())
+		})
+	})
+})
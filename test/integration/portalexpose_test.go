@@ -1,17 +1,26 @@
 package integration
 
 import (
+	"context"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/controller"
+	"github.com/gosuda/portal-expose/internal/tunnel"
+	"github.com/gosuda/portal-expose/internal/util"
 )
 
 var _ = Describe("PortalExpose Controller", func() {
@@ -44,8 +53,7 @@ var _ = Describe("PortalExpose Controller", func() {
 			tunnelClassName := "default-tunnel-class"
 			tunnelClass := &portalv1alpha1.TunnelClass{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      tunnelClassName,
-					Namespace: namespace,
+					Name: tunnelClassName,
 					Annotations: map[string]string{
 						"portal.gosuda.org/is-default-class": "true",
 					},
@@ -69,14 +77,14 @@ var _ = Describe("PortalExpose Controller", func() {
 						Name: "test-app",
 						Service: portalv1alpha1.ServiceRef{
 							Name: serviceName,
-							Port: 80,
+							Port: intstr.FromInt32(80),
 						},
 					},
 					Relay: portalv1alpha1.RelaySpec{
 						Targets: []portalv1alpha1.RelayTarget{
 							{
 								Name: "test-relay",
-								URL:  "wss://portal.gosuda.org/relay",
+								URL:  connectedRelayURL,
 							},
 						},
 					},
@@ -100,6 +108,31 @@ var _ = Describe("PortalExpose Controller", func() {
 			Expect(createdDeployment.OwnerReferences).To(HaveLen(1))
 			Expect(createdDeployment.OwnerReferences[0].Name).To(Equal(portalExposeName))
 
+			By("Verifying the PodDisruptionBudget is created")
+			createdPDB := &policyv1.PodDisruptionBudget{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, deploymentLookupKey, createdPDB)
+			}, timeout, interval).Should(Succeed())
+			Expect(createdPDB.Spec.MaxUnavailable).NotTo(BeNil())
+			Expect(createdPDB.Spec.MaxUnavailable.IntValue()).To(Equal(1))
+			Expect(createdPDB.Spec.Selector.MatchLabels).To(Equal(createdDeployment.Spec.Selector.MatchLabels))
+			Expect(createdPDB.OwnerReferences).To(HaveLen(1))
+			Expect(createdPDB.OwnerReferences[0].Name).To(Equal(portalExposeName))
+
+			By("Simulating a running tunnel pod backed by the fake status server")
+			tunnelPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      deploymentName + "-0",
+					Namespace: namespace,
+					Labels:    createdDeployment.Spec.Selector.MatchLabels,
+				},
+				Spec: createdDeployment.Spec.Template.Spec,
+			}
+			Expect(k8sClient.Create(ctx, tunnelPod)).Should(Succeed())
+			tunnelPod.Status.Phase = corev1.PodRunning
+			tunnelPod.Status.PodIP = "127.0.0.1"
+			Expect(k8sClient.Status().Update(ctx, tunnelPod)).Should(Succeed())
+
 			By("Simulating Pod readiness")
 			// In a real cluster, the controller would see the Pods becoming ready.
 			// Here we manually update the Deployment status to simulate this.
@@ -122,6 +155,42 @@ var _ = Describe("PortalExpose Controller", func() {
 			By("Verifying PublicURL is generated")
 			Expect(updatedPortalExpose.Status.PublicURL).To(Equal("https://test-app.portal.gosuda.org"))
 
+			By("Verifying the status records the observed generation")
+			Expect(updatedPortalExpose.Status.ObservedGeneration).To(Equal(updatedPortalExpose.Generation))
+			for _, condition := range updatedPortalExpose.Status.Conditions {
+				Expect(condition.ObservedGeneration).To(Equal(updatedPortalExpose.Generation), condition.Type)
+			}
+
+			By("Verifying the tunnel identity is generated")
+			identity := &corev1.Secret{}
+			identityKey := types.NamespacedName{Name: portalExposeName + "-tunnel-identity", Namespace: namespace}
+			Expect(k8sClient.Get(ctx, identityKey, identity)).Should(Succeed())
+			Expect(updatedPortalExpose.Status.IdentityFingerprint).To(HavePrefix("SHA256:"))
+			firstFingerprint := updatedPortalExpose.Status.IdentityFingerprint
+
+			By("Verifying relay status is read from the tunnel pod")
+			Expect(updatedPortalExpose.Status.Relay.Connected).To(HaveLen(1))
+			Expect(updatedPortalExpose.Status.Relay.Connected[0].Status).To(Equal("Connected"))
+			Expect(updatedPortalExpose.Status.Relay.Connected[0].ConnectedAt).NotTo(BeNil())
+
+			By("Requesting an identity rotation")
+			Expect(k8sClient.Get(ctx, portalExposeLookupKey, updatedPortalExpose)).Should(Succeed())
+			updatedPortalExpose.Annotations = map[string]string{tunnel.RotateIdentityAnnotation: "1"}
+			Expect(k8sClient.Update(ctx, updatedPortalExpose)).Should(Succeed())
+
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, portalExposeLookupKey, updatedPortalExpose); err != nil {
+					return ""
+				}
+				return updatedPortalExpose.Status.IdentityFingerprint
+			}, timeout, interval).ShouldNot(Or(BeEmpty(), Equal(firstFingerprint)))
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, deploymentLookupKey, createdDeployment); err != nil {
+					return ""
+				}
+				return createdDeployment.Spec.Template.Annotations[tunnel.IdentityFingerprintAnnotation]
+			}, timeout, interval).Should(Equal(updatedPortalExpose.Status.IdentityFingerprint))
+
 			By("Deleting the PortalExpose")
 			Expect(k8sClient.Delete(ctx, portalExpose)).Should(Succeed())
 
@@ -131,12 +200,522 @@ var _ = Describe("PortalExpose Controller", func() {
 				return client.IgnoreNotFound(err) == nil
 			}, timeout, interval).Should(BeTrue())
 
-			// Clean up Service and TunnelClass
+			By("Verifying the tunnel identity is deleted")
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, identityKey, identity)
+				return client.IgnoreNotFound(err) == nil && err != nil
+			}, timeout, interval).Should(BeTrue())
+
+			By("Verifying the PodDisruptionBudget is deleted")
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, deploymentLookupKey, createdPDB)
+				return client.IgnoreNotFound(err) == nil && err != nil
+			}, timeout, interval).Should(BeTrue())
+
+			// Clean up tunnel pod, Service and TunnelClass
+			Expect(k8sClient.Delete(ctx, tunnelPod)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, service)).Should(Succeed())
+			deleteTunnelClass(tunnelClass)
+		})
+	})
+
+	Context("When the TunnelClass enables autoscaling", func() {
+		It("Should create an HPA and leave the replica count to it", func() {
+			namespace := "default"
+
+			By("Creating a Service and an autoscaling default TunnelClass")
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "autoscaled-service", Namespace: namespace},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+			}
+			Expect(k8sClient.Create(ctx, service)).Should(Succeed())
+
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "autoscaled-tunnel-class",
+					Annotations: map[string]string{"portal.gosuda.org/is-default-class": "true"},
+				},
+				Spec: portalv1alpha1.TunnelClassSpec{
+					Replicas: 3,
+					Size:     "small",
+					Autoscaling: &portalv1alpha1.TunnelAutoscaling{
+						MinReplicas: 2,
+						MaxReplicas: 6,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, tunnelClass)).Should(Succeed())
+
+			By("Creating a PortalExpose using the default class")
+			portalExpose := &portalv1alpha1.PortalExpose{
+				ObjectMeta: metav1.ObjectMeta{Name: "autoscaled-app", Namespace: namespace},
+				Spec: portalv1alpha1.PortalExposeSpec{
+					App: portalv1alpha1.AppSpec{
+						Name:    "autoscaled-app",
+						Service: portalv1alpha1.ServiceRef{Name: service.Name, Port: intstr.FromInt32(80)},
+					},
+					Relay: portalv1alpha1.RelaySpec{
+						Targets: []portalv1alpha1.RelayTarget{{Name: "test-relay", URL: connectedRelayURL}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, portalExpose)).Should(Succeed())
+
+			By("Verifying the HPA targets the tunnel Deployment")
+			key := types.NamespacedName{Name: "autoscaled-app-tunnel", Namespace: namespace}
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, key, hpa)
+			}, timeout, interval).Should(Succeed())
+			Expect(hpa.Spec.ScaleTargetRef.Name).To(Equal(key.Name))
+			Expect(hpa.Spec.MinReplicas).To(Equal(int32Ptr(2)))
+			Expect(hpa.Spec.MaxReplicas).To(Equal(int32(6)))
+
+			By("Verifying the Deployment starts at minReplicas")
+			deployment := &appsv1.Deployment{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, key, deployment)
+			}, timeout, interval).Should(Succeed())
+			Expect(deployment.Spec.Replicas).To(Equal(int32Ptr(2)))
+
+			By("Scaling the Deployment as the HPA would")
+			deployment.Spec.Replicas = int32Ptr(5)
+			Expect(k8sClient.Update(ctx, deployment)).Should(Succeed())
+
+			By("Verifying the controller keeps the HPA replica count")
+			Consistently(func() int32 {
+				if err := k8sClient.Get(ctx, key, deployment); err != nil {
+					return 0
+				}
+				return *deployment.Spec.Replicas
+			}, 2*time.Second, interval).Should(Equal(int32(5)))
+
+			By("Disabling autoscaling")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(tunnelClass), tunnelClass)).Should(Succeed())
+			tunnelClass.Spec.Autoscaling = nil
+			Expect(k8sClient.Update(ctx, tunnelClass)).Should(Succeed())
+
+			By("Verifying the HPA is deleted and the class replicas apply")
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, key, hpa)
+				return client.IgnoreNotFound(err) == nil && err != nil
+			}, timeout, interval).Should(BeTrue())
+			Eventually(func() int32 {
+				if err := k8sClient.Get(ctx, key, deployment); err != nil {
+					return 0
+				}
+				return *deployment.Spec.Replicas
+			}, timeout, interval).Should(Equal(int32(3)))
+
+			Expect(k8sClient.Delete(ctx, portalExpose)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, service)).Should(Succeed())
+			deleteTunnelClass(tunnelClass)
+		})
+	})
+
+	Context("When a relay target references credentials", func() {
+		It("Should wait for the Secret and roll the tunnel on rotation", func() {
+			namespace := "default"
+
+			By("Creating a Service and a default TunnelClass")
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "private-service", Namespace: namespace},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+			}
+			Expect(k8sClient.Create(ctx, service)).Should(Succeed())
+
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "private-tunnel-class",
+					Annotations: map[string]string{"portal.gosuda.org/is-default-class": "true"},
+				},
+				Spec: portalv1alpha1.TunnelClassSpec{Replicas: 1, Size: "small"},
+			}
+			Expect(k8sClient.Create(ctx, tunnelClass)).Should(Succeed())
+
+			By("Creating a PortalExpose whose credentials Secret does not exist")
+			portalExpose := &portalv1alpha1.PortalExpose{
+				ObjectMeta: metav1.ObjectMeta{Name: "private-app", Namespace: namespace},
+				Spec: portalv1alpha1.PortalExposeSpec{
+					App: portalv1alpha1.AppSpec{
+						Name:    "private-app",
+						Service: portalv1alpha1.ServiceRef{Name: service.Name, Port: intstr.FromInt32(80)},
+					},
+					Relay: portalv1alpha1.RelaySpec{
+						Targets: []portalv1alpha1.RelayTarget{{
+							Name:                 "private-relay",
+							URL:                  connectedRelayURL,
+							CredentialsSecretRef: &portalv1alpha1.RelayCredentialsReference{Name: "private-relay-token"},
+						}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, portalExpose)).Should(Succeed())
+
+			By("Verifying CredentialsMissing is True")
+			Eventually(func() bool {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(portalExpose), portalExpose); err != nil {
+					return false
+				}
+				return util.IsConditionTrue(portalExpose.Status.Conditions, util.ConditionCredentialsMissing)
+			}, timeout, interval).Should(BeTrue())
+
+			By("Creating the Secret")
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "private-relay-token", Namespace: namespace},
+				Data:       map[string][]byte{"token": []byte("first")},
+			}
+			Expect(k8sClient.Create(ctx, secret)).Should(Succeed())
+
+			By("Verifying the Deployment mounts the Secret and records its hash")
+			key := types.NamespacedName{Name: "private-app-tunnel", Namespace: namespace}
+			deployment := &appsv1.Deployment{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, key, deployment)
+			}, timeout, interval).Should(Succeed())
+			firstHash := deployment.Spec.Template.Annotations[tunnel.CredentialsHashAnnotation]
+			Expect(firstHash).NotTo(BeEmpty())
+			Expect(deployment.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(
+				HaveField("MountPath", "/etc/portal/relays/private-relay")))
+
+			By("Verifying the Secret is labeled into the controller cache")
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), secret); err != nil {
+					return ""
+				}
+				return secret.Labels[tunnel.SecretLabel]
+			}, timeout, interval).Should(Equal("true"))
+
+			By("Rotating the Secret")
+			secret.Data["token"] = []byte("second")
+			Expect(k8sClient.Update(ctx, secret)).Should(Succeed())
+
+			By("Verifying the pod template hash changes")
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, key, deployment); err != nil {
+					return ""
+				}
+				return deployment.Spec.Template.Annotations[tunnel.CredentialsHashAnnotation]
+			}, timeout, interval).ShouldNot(Or(BeEmpty(), Equal(firstHash)))
+
+			Expect(k8sClient.Delete(ctx, portalExpose)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, secret)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, service)).Should(Succeed())
+			deleteTunnelClass(tunnelClass)
+		})
+	})
+
+	Context("When another client edits the tunnel Deployment", func() {
+		It("Should revert controller-owned fields and keep the others", func() {
+			namespace := "default"
+
+			By("Creating a Service and a default TunnelClass")
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "drift-service", Namespace: namespace},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+			}
+			Expect(k8sClient.Create(ctx, service)).Should(Succeed())
+
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "drift-tunnel-class",
+					Annotations: map[string]string{"portal.gosuda.org/is-default-class": "true"},
+				},
+				Spec: portalv1alpha1.TunnelClassSpec{Replicas: 1, Size: "small"},
+			}
+			Expect(k8sClient.Create(ctx, tunnelClass)).Should(Succeed())
+
+			By("Creating a PortalExpose using the default class")
+			portalExpose := &portalv1alpha1.PortalExpose{
+				ObjectMeta: metav1.ObjectMeta{Name: "drift-app", Namespace: namespace},
+				Spec: portalv1alpha1.PortalExposeSpec{
+					App: portalv1alpha1.AppSpec{
+						Name:    "drift-app",
+						Service: portalv1alpha1.ServiceRef{Name: service.Name, Port: intstr.FromInt32(80)},
+					},
+					Relay: portalv1alpha1.RelaySpec{
+						Targets: []portalv1alpha1.RelayTarget{{Name: "test-relay", URL: connectedRelayURL}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, portalExpose)).Should(Succeed())
+
+			key := types.NamespacedName{Name: "drift-app-tunnel", Namespace: namespace}
+			deployment := &appsv1.Deployment{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, key, deployment)
+			}, timeout, interval).Should(Succeed())
+			args := deployment.Spec.Template.Spec.Containers[0].Args
+
+			By("Editing the args, adding a sidecar and a restart annotation")
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, key, deployment); err != nil {
+					return err
+				}
+				template := &deployment.Spec.Template
+				template.Spec.Containers[0].Args = []string{"--relay", "wss://elsewhere.example.com"}
+				template.Spec.Containers = append(template.Spec.Containers, corev1.Container{
+					Name:  "injected-sidecar",
+					Image: "busybox",
+				})
+				template.Annotations["kubectl.kubernetes.io/restartedAt"] = "2025-01-01T00:00:00Z"
+				return k8sClient.Update(ctx, deployment)
+			}, timeout, interval).Should(Succeed())
+
+			By("Verifying the controller restores its args")
+			Eventually(func() []string {
+				if err := k8sClient.Get(ctx, key, deployment); err != nil {
+					return nil
+				}
+				return deployment.Spec.Template.Spec.Containers[0].Args
+			}, timeout, interval).Should(Equal(args))
+
+			By("Verifying the fields owned by the other client are kept")
+			Expect(deployment.Spec.Template.Spec.Containers).To(ContainElement(HaveField("Name", "injected-sidecar")))
+			Expect(deployment.Spec.Template.Annotations).To(HaveKey("kubectl.kubernetes.io/restartedAt"))
+
+			Expect(k8sClient.Delete(ctx, portalExpose)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, service)).Should(Succeed())
+			deleteTunnelClass(tunnelClass)
+		})
+	})
+
+	Context("When the HPA scales between the Deployment read and the apply", func() {
+		It("Should not reset the scaled replica count", func() {
+			namespace := "default"
+
+			By("Creating a Service, an autoscaling default TunnelClass and a PortalExpose")
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "race-service", Namespace: namespace},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+			}
+			Expect(k8sClient.Create(ctx, service)).Should(Succeed())
+
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "race-tunnel-class",
+					Annotations: map[string]string{"portal.gosuda.org/is-default-class": "true"},
+				},
+				Spec: portalv1alpha1.TunnelClassSpec{
+					Replicas:    1,
+					Size:        "small",
+					Autoscaling: &portalv1alpha1.TunnelAutoscaling{MinReplicas: 2, MaxReplicas: 6},
+				},
+			}
+			Expect(k8sClient.Create(ctx, tunnelClass)).Should(Succeed())
+
+			portalExpose := &portalv1alpha1.PortalExpose{
+				ObjectMeta: metav1.ObjectMeta{Name: "race-app", Namespace: namespace},
+				Spec: portalv1alpha1.PortalExposeSpec{
+					App: portalv1alpha1.AppSpec{
+						Name:    "race-app",
+						Service: portalv1alpha1.ServiceRef{Name: service.Name, Port: intstr.FromInt32(80)},
+					},
+					Relay: portalv1alpha1.RelaySpec{
+						Targets: []portalv1alpha1.RelayTarget{{Name: "test-relay", URL: connectedRelayURL}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, portalExpose)).Should(Succeed())
+
+			By("Waiting until the controllers' cache holds the Deployment at minReplicas")
+			key := types.NamespacedName{Name: "race-app-tunnel", Namespace: namespace}
+			deployment := &appsv1.Deployment{}
+			Eventually(func() *int32 {
+				if err := mgrClient.Get(ctx, key, deployment); err != nil {
+					return nil
+				}
+				return deployment.Spec.Replicas
+			}, timeout, interval).Should(Equal(int32Ptr(2)))
+
+			By("Reconciling with a client that scales the Deployment right before the apply")
+			reconciler := &controller.PortalExposeReconciler{
+				Client: &scaleBeforeApply{Client: mgrClient, scale: func() {
+					scaled := &appsv1.Deployment{}
+					Expect(k8sClient.Get(ctx, key, scaled)).To(Succeed())
+					scaled.Spec.Replicas = int32Ptr(5)
+					Expect(k8sClient.Update(ctx, scaled, client.FieldOwner("horizontal-pod-autoscaler"))).To(Succeed())
+				}},
+				Scheme:   mgrClient.Scheme(),
+				Recorder: record.NewFakeRecorder(100),
+			}
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(portalExpose)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(BeTrue())
+
+			By("Verifying the stale apply did not reset the HPA count")
+			Consistently(func() int32 {
+				if err := k8sClient.Get(ctx, key, deployment); err != nil {
+					return 0
+				}
+				return *deployment.Spec.Replicas
+			}, 2*time.Second, interval).Should(Equal(int32(5)))
+
+			Expect(k8sClient.Delete(ctx, portalExpose)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, service)).Should(Succeed())
+			deleteTunnelClass(tunnelClass)
+		})
+	})
+
+	Context("When the tunnel Deployment was written by Update calls of an earlier controller", func() {
+		It("Should take over its fields and prune the ones no longer desired", func() {
+			namespace := "default"
+
+			By("Creating a Service and a default TunnelClass")
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "legacy-service", Namespace: namespace},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+			}
+			Expect(k8sClient.Create(ctx, service)).Should(Succeed())
+
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "legacy-tunnel-class",
+					Annotations: map[string]string{"portal.gosuda.org/is-default-class": "true"},
+				},
+				Spec: portalv1alpha1.TunnelClassSpec{Replicas: 1, Size: "small"},
+			}
+			Expect(k8sClient.Create(ctx, tunnelClass)).Should(Succeed())
+
+			portalExpose := &portalv1alpha1.PortalExpose{
+				ObjectMeta: metav1.ObjectMeta{Name: "legacy-app", Namespace: namespace},
+				Spec: portalv1alpha1.PortalExposeSpec{
+					App: portalv1alpha1.AppSpec{
+						Name:    "legacy-app",
+						Service: portalv1alpha1.ServiceRef{Name: service.Name, Port: intstr.FromInt32(80)},
+					},
+					Relay: portalv1alpha1.RelaySpec{
+						Targets: []portalv1alpha1.RelayTarget{{Name: "test-relay", URL: connectedRelayURL}},
+					},
+				},
+			}
+
+			By("Creating the tunnel Deployment with a plain Create, as controllers before server-side apply did")
+			// The test client shares the default field manager name of the controllers in this process
+			labels := tunnel.PodLabels(portalExpose)
+			legacyLabels := map[string]string{"legacy": "true"}
+			for k, v := range labels {
+				legacyLabels[k] = v
+			}
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "legacy-app-tunnel", Namespace: namespace, Labels: legacyLabels},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: int32Ptr(1),
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec: corev1.PodSpec{
+							NodeSelector: map[string]string{"legacy-pool": "true"},
+							Containers: []corev1.Container{{
+								Name:  "tunnel",
+								Image: "busybox",
+								Env:   []corev1.EnvVar{{Name: "LEGACY", Value: "true"}},
+							}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).Should(Succeed())
+			Expect(k8sClient.Create(ctx, portalExpose)).Should(Succeed())
+
+			By("Verifying the fields dropped from the desired state are pruned")
+			key := client.ObjectKeyFromObject(deployment)
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, key, deployment)).To(Succeed())
+				g.Expect(deployment.Labels).NotTo(HaveKey("legacy"))
+				g.Expect(deployment.Spec.Template.Spec.NodeSelector).NotTo(HaveKey("legacy-pool"))
+				g.Expect(deployment.Spec.Template.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "LEGACY")))
+			}, timeout, interval).Should(Succeed())
+
+			Expect(k8sClient.Delete(ctx, portalExpose)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, deployment)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, service)).Should(Succeed())
+			deleteTunnelClass(tunnelClass)
+		})
+	})
+
+	Context("When the Service and TunnelClass change after the PortalExpose", func() {
+		It("Should re-reconcile the dependent PortalExpose", func() {
+			namespace := "default"
+
+			By("Creating a PortalExpose before its Service")
+			portalExpose := &portalv1alpha1.PortalExpose{
+				ObjectMeta: metav1.ObjectMeta{Name: "late-app", Namespace: namespace},
+				Spec: portalv1alpha1.PortalExposeSpec{
+					App: portalv1alpha1.AppSpec{
+						Name:    "late-app",
+						Service: portalv1alpha1.ServiceRef{Name: "late-service", Port: intstr.FromInt32(80)},
+					},
+					Relay: portalv1alpha1.RelaySpec{
+						Targets: []portalv1alpha1.RelayTarget{{Name: "test-relay", URL: connectedRelayURL}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, portalExpose)).Should(Succeed())
+
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(portalExpose), portalExpose); err != nil {
+					return ""
+				}
+				return portalExpose.Status.Phase
+			}, timeout, interval).Should(Equal(util.PhaseFailed))
+
+			By("Creating the default TunnelClass and the Service")
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "late-tunnel-class",
+					Annotations: map[string]string{"portal.gosuda.org/is-default-class": "true"},
+				},
+				Spec: portalv1alpha1.TunnelClassSpec{Replicas: 1, Size: "small"},
+			}
+			Expect(k8sClient.Create(ctx, tunnelClass)).Should(Succeed())
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "late-service", Namespace: namespace},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+			}
+			Expect(k8sClient.Create(ctx, service)).Should(Succeed())
+
+			By("Verifying the tunnel Deployment is created")
+			key := types.NamespacedName{Name: "late-app-tunnel", Namespace: namespace}
+			deployment := &appsv1.Deployment{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, key, deployment)
+			}, timeout, interval).Should(Succeed())
+
+			By("Editing the TunnelClass")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(tunnelClass), tunnelClass)).Should(Succeed())
+			tunnelClass.Spec.Replicas = 2
+			Expect(k8sClient.Update(ctx, tunnelClass)).Should(Succeed())
+
+			By("Verifying the change reaches the tunnel Deployment")
+			Eventually(func() int32 {
+				if err := k8sClient.Get(ctx, key, deployment); err != nil {
+					return 0
+				}
+				return *deployment.Spec.Replicas
+			}, timeout, interval).Should(Equal(int32(2)))
+
+			Expect(k8sClient.Delete(ctx, portalExpose)).Should(Succeed())
 			Expect(k8sClient.Delete(ctx, service)).Should(Succeed())
-			Expect(k8sClient.Delete(ctx, tunnelClass)).Should(Succeed())
+			deleteTunnelClass(tunnelClass)
 		})
 	})
 })
 
 // Helper to create int32 pointer
 func int32Ptr(i int32) *int32 { return &i }
+
+// scaleBeforeApply scales the tunnel Deployment right before the controller applies it,
+// as an HPA racing the reconcile would
+type scaleBeforeApply struct {
+	client.Client
+	scale func()
+}
+
+func (c *scaleBeforeApply) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if _, ok := obj.(*appsv1.Deployment); ok && patch.Type() == types.ApplyPatchType && c.scale != nil {
+		c.scale()
+		c.scale = nil
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
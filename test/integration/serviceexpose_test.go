@@ -0,0 +1,120 @@
+package integration
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/autoexpose"
+)
+
+var _ = Describe("Service auto-exposure", func() {
+	const (
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	Context("When a Service is annotated for exposure", func() {
+		It("Should create and delete the owned PortalExpose with the annotation", func() {
+			namespace := "default"
+
+			By("Creating an annotated Service")
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "annotated-service",
+					Namespace: namespace,
+					Annotations: map[string]string{
+						autoexpose.ExposeAnnotation:  "true",
+						autoexpose.AppNameAnnotation: "annotated-app",
+						autoexpose.PortAnnotation:    "http",
+						autoexpose.RelayAnnotation:   "wss://portal.gosuda.org/relay",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Name: "http", Port: 80}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, service)).Should(Succeed())
+
+			By("Verifying the PortalExpose is generated from the annotations")
+			portalExposeKey := client.ObjectKeyFromObject(service)
+			portalExpose := &portalv1alpha1.PortalExpose{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, portalExposeKey, portalExpose)
+			}, timeout, interval).Should(Succeed())
+			Expect(portalExpose.Spec.App.Name).To(Equal("annotated-app"))
+			Expect(portalExpose.Spec.App.Service.Port).To(Equal(intstr.FromString("http")))
+			Expect(portalExpose.Spec.Relay.Targets).To(HaveLen(1))
+			Expect(metav1.IsControlledBy(portalExpose, service)).To(BeTrue())
+
+			By("Changing the app name annotation")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(service), service)).Should(Succeed())
+			service.Annotations[autoexpose.AppNameAnnotation] = "renamed-app"
+			Expect(k8sClient.Update(ctx, service)).Should(Succeed())
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, portalExposeKey, portalExpose); err != nil {
+					return ""
+				}
+				return portalExpose.Spec.App.Name
+			}, timeout, interval).Should(Equal("renamed-app"))
+
+			By("Removing the expose annotation")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(service), service)).Should(Succeed())
+			delete(service.Annotations, autoexpose.ExposeAnnotation)
+			Expect(k8sClient.Update(ctx, service)).Should(Succeed())
+
+			By("Verifying the PortalExpose is deleted")
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, portalExposeKey, &portalv1alpha1.PortalExpose{})
+				return errors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(k8sClient.Delete(ctx, service)).Should(Succeed())
+		})
+
+		It("Should follow the first Service port without a port annotation", func() {
+			By("Creating an annotated Service without a port annotation")
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "unported-service",
+					Namespace: "default",
+					Annotations: map[string]string{
+						autoexpose.ExposeAnnotation: "true",
+						autoexpose.RelayAnnotation:  "wss://portal.gosuda.org/relay",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Name: "http", Port: 80}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, service)).Should(Succeed())
+
+			portalExposeKey := client.ObjectKeyFromObject(service)
+			portalExpose := &portalv1alpha1.PortalExpose{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, portalExposeKey, portalExpose)
+			}, timeout, interval).Should(Succeed())
+			Expect(portalExpose.Spec.App.Service.Port).To(Equal(intstr.FromInt32(80)))
+
+			By("Changing the Service port")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(service), service)).Should(Succeed())
+			service.Spec.Ports[0].Port = 8080
+			Expect(k8sClient.Update(ctx, service)).Should(Succeed())
+			Eventually(func() intstr.IntOrString {
+				if err := k8sClient.Get(ctx, portalExposeKey, portalExpose); err != nil {
+					return intstr.IntOrString{}
+				}
+				return portalExpose.Spec.App.Service.Port
+			}, timeout, interval).Should(Equal(intstr.FromInt32(8080)))
+
+			Expect(k8sClient.Delete(ctx, service)).Should(Succeed())
+		})
+	})
+})
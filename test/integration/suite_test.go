@@ -18,14 +18,23 @@ package integration
 
 import (
 	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -33,6 +42,7 @@ import (
 
 	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
 	"github.com/gosuda/portal-expose/internal/controller"
+	"github.com/gosuda/portal-expose/internal/tunnel"
 	ctrl "sigs.k8s.io/controller-runtime"
 	// +kubebuilder:scaffold:imports
 )
@@ -42,9 +52,29 @@ import (
 
 var cfg *rest.Config
 var k8sClient client.Client
+
+// mgrClient is the cached client of the controllers, backed by their field indexes
+var mgrClient client.Client
 var testEnv *envtest.Environment
 var ctx context.Context
 var cancel context.CancelFunc
+var statusServer *httptest.Server
+
+// connectedRelayURL is reported as an established session by the fake tunnel status server
+const connectedRelayURL = "wss://portal.gosuda.org/relay"
+
+// newFakeTunnelStatusServer serves the tunnel status endpoint in-process so that
+// tunnel pods pointed at 127.0.0.1 report their relay sessions without a real tunnel
+func newFakeTunnelStatusServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connectedAt := metav1.Now()
+		_ = json.NewEncoder(w).Encode(tunnel.StatusReport{
+			Relays: []tunnel.RelaySession{
+				{URL: connectedRelayURL, Connected: true, ConnectedAt: &connectedAt},
+			},
+		})
+	}))
+}
 
 func TestIntegration(t *testing.T) {
 	RegisterFailHandler(Fail)
@@ -77,25 +107,63 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
 
+	// Start the fake tunnel status endpoint
+	statusServer = newFakeTunnelStatusServer()
+	_, statusPort, err := net.SplitHostPort(statusServer.Listener.Addr().String())
+	Expect(err).NotTo(HaveOccurred())
+	port, err := strconv.Atoi(statusPort)
+	Expect(err).NotTo(HaveOccurred())
+	statusClient := tunnel.NewHTTPStatusClient()
+	statusClient.Port = int32(port)
+
 	// Start the controllers
 	k8sManager, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme: scheme.Scheme,
+		Cache:  cache.Options{ByObject: controller.CacheByObject()},
 	})
 	Expect(err).ToNot(HaveOccurred())
 
+	mgrClient = k8sManager.GetClient()
+
+	err = controller.SetupIndexes(ctx, k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
 	err = (&controller.PortalExposeReconciler{
+		Client:       k8sManager.GetClient(),
+		Scheme:       k8sManager.GetScheme(),
+		Recorder:     k8sManager.GetEventRecorderFor("portalexpose-controller"),
+		StatusClient: statusClient,
+		APIReader:    k8sManager.GetAPIReader(),
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&controller.TunnelClassReconciler{
 		Client:   k8sManager.GetClient(),
 		Scheme:   k8sManager.GetScheme(),
-		Recorder: k8sManager.GetEventRecorderFor("portalexpose-controller"),
+		Recorder: k8sManager.GetEventRecorderFor("tunnelclass-controller"),
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
-	err = (&controller.TunnelClassReconciler{
+	err = (&controller.ClusterRelayReconciler{
 		Client: k8sManager.GetClient(),
 		Scheme: k8sManager.GetScheme(),
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
+	err = (&controller.ServiceExposeReconciler{
+		Client:   k8sManager.GetClient(),
+		Scheme:   k8sManager.GetScheme(),
+		Recorder: k8sManager.GetEventRecorderFor("service-expose-controller"),
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&controller.IngressReconciler{
+		Client:   k8sManager.GetClient(),
+		Scheme:   k8sManager.GetScheme(),
+		Recorder: k8sManager.GetEventRecorderFor("ingress-controller"),
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
 	go func() {
 		defer GinkgoRecover()
 		err = k8sManager.Start(ctx)
@@ -106,7 +174,20 @@ var _ = BeforeSuite(func() {
 
 var _ = AfterSuite(func() {
 	cancel()
+	if statusServer != nil {
+		statusServer.Close()
+	}
 	By("tearing down the test environment")
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
 })
+
+// deleteTunnelClass deletes a TunnelClass and waits until its in-use finalizer releases it,
+// so later specs never resolve a default class that is still being deleted
+func deleteTunnelClass(tunnelClass *portalv1alpha1.TunnelClass) {
+	Expect(k8sClient.Delete(ctx, tunnelClass)).Should(Succeed())
+	Eventually(func() bool {
+		err := k8sClient.Get(ctx, client.ObjectKeyFromObject(tunnelClass), &portalv1alpha1.TunnelClass{})
+		return apierrors.IsNotFound(err)
+	}, 10*time.Second, 250*time.Millisecond).Should(BeTrue())
+}
@@ -0,0 +1,259 @@
+package integration
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	portalv1alpha1 "github.com/gosuda/portal-expose/api/v1alpha1"
+	"github.com/gosuda/portal-expose/internal/util"
+)
+
+var _ = Describe("TunnelClass Controller", func() {
+	const (
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	Context("When a TunnelClass names an unknown size tier", func() {
+		It("Should set the InvalidSize condition", func() {
+			By("Creating a TunnelClass with an unknown size")
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{Name: "xlarge-tunnel-class"},
+				Spec: portalv1alpha1.TunnelClassSpec{
+					Replicas: 1,
+					Size:     "xlarge",
+				},
+			}
+			Expect(k8sClient.Create(ctx, tunnelClass)).Should(Succeed())
+
+			By("Verifying InvalidSize is True")
+			Eventually(func() bool {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(tunnelClass), tunnelClass); err != nil {
+					return false
+				}
+				return util.IsConditionTrue(tunnelClass.Status.Conditions, util.ConditionInvalidSize)
+			}, timeout, interval).Should(BeTrue())
+
+			By("Switching to a known size")
+			tunnelClass.Spec.Size = "medium"
+			Expect(k8sClient.Update(ctx, tunnelClass)).Should(Succeed())
+
+			By("Verifying InvalidSize is False")
+			Eventually(func() metav1.ConditionStatus {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(tunnelClass), tunnelClass); err != nil {
+					return ""
+				}
+				condition := util.FindCondition(tunnelClass.Status.Conditions, util.ConditionInvalidSize)
+				if condition == nil {
+					return ""
+				}
+				return condition.Status
+			}, timeout, interval).Should(Equal(metav1.ConditionFalse))
+
+			deleteTunnelClass(tunnelClass)
+		})
+	})
+
+	Context("When PortalExposes use a TunnelClass", func() {
+		It("Should count them and block deletion until the last one is gone", func() {
+			namespace := "default"
+
+			By("Creating a default TunnelClass, a Service and a PortalExpose")
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "in-use-tunnel-class",
+					Annotations: map[string]string{"portal.gosuda.org/is-default-class": "true"},
+				},
+				Spec: portalv1alpha1.TunnelClassSpec{Replicas: 2, Size: "small"},
+			}
+			Expect(k8sClient.Create(ctx, tunnelClass)).Should(Succeed())
+
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "in-use-service", Namespace: namespace},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+			}
+			Expect(k8sClient.Create(ctx, service)).Should(Succeed())
+
+			portalExpose := &portalv1alpha1.PortalExpose{
+				ObjectMeta: metav1.ObjectMeta{Name: "in-use-app", Namespace: namespace},
+				Spec: portalv1alpha1.PortalExposeSpec{
+					App: portalv1alpha1.AppSpec{
+						Name:    "in-use-app",
+						Service: portalv1alpha1.ServiceRef{Name: service.Name, Port: intstr.FromInt32(80)},
+					},
+					Relay: portalv1alpha1.RelaySpec{
+						Targets: []portalv1alpha1.RelayTarget{{Name: "test-relay", URL: connectedRelayURL}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, portalExpose)).Should(Succeed())
+
+			By("Verifying the class is Valid and counts the PortalExpose")
+			Eventually(func() int32 {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(tunnelClass), tunnelClass); err != nil {
+					return -1
+				}
+				return tunnelClass.Status.ExposureCount
+			}, timeout, interval).Should(Equal(int32(1)))
+			Expect(util.IsConditionTrue(tunnelClass.Status.Conditions, util.ConditionValid)).To(BeTrue())
+			Expect(tunnelClass.Finalizers).To(ContainElement(util.TunnelClassFinalizerName))
+
+			By("Deleting the class while it is in use")
+			Expect(k8sClient.Delete(ctx, tunnelClass)).Should(Succeed())
+			Consistently(func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(tunnelClass), tunnelClass)
+			}, 2*time.Second, interval).Should(Succeed())
+
+			By("Deleting the PortalExpose releases the class")
+			Expect(k8sClient.Delete(ctx, portalExpose)).Should(Succeed())
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, client.ObjectKeyFromObject(tunnelClass), tunnelClass)
+				return errors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(k8sClient.Delete(ctx, service)).Should(Succeed())
+		})
+	})
+
+	Context("When two TunnelClasses are marked default", func() {
+		It("Should select the older one and report the conflict on the other", func() {
+			conflictStatus := func(tunnelClass *portalv1alpha1.TunnelClass) func() metav1.ConditionStatus {
+				return func() metav1.ConditionStatus {
+					if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(tunnelClass), tunnelClass); err != nil {
+						return ""
+					}
+					condition := util.FindCondition(tunnelClass.Status.Conditions, util.ConditionDefaultConflict)
+					if condition == nil {
+						return ""
+					}
+					return condition.Status
+				}
+			}
+
+			By("Creating two default TunnelClasses")
+			// Names break the tie when both are created within the same second
+			older := &portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "conflict-default-a",
+					Annotations: map[string]string{"portal.gosuda.org/is-default-class": "true"},
+				},
+				Spec: portalv1alpha1.TunnelClassSpec{Replicas: 2, Size: "small"},
+			}
+			Expect(k8sClient.Create(ctx, older)).Should(Succeed())
+			newer := &portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "conflict-default-b",
+					Annotations: map[string]string{"portal.gosuda.org/is-default-class": "true"},
+				},
+				Spec: portalv1alpha1.TunnelClassSpec{Replicas: 2, Size: "small"},
+			}
+			Expect(k8sClient.Create(ctx, newer)).Should(Succeed())
+
+			By("Verifying the newer class reports the conflict and keeps its annotation")
+			Eventually(conflictStatus(newer), timeout, interval).Should(Equal(metav1.ConditionTrue))
+			Eventually(conflictStatus(older), timeout, interval).Should(Equal(metav1.ConditionFalse))
+			Expect(newer.Annotations).To(HaveKeyWithValue("portal.gosuda.org/is-default-class", "true"))
+
+			By("Deleting the selected class hands the default to the other one")
+			deleteTunnelClass(older)
+			Eventually(conflictStatus(newer), timeout, interval).Should(Equal(metav1.ConditionFalse))
+
+			deleteTunnelClass(newer)
+		})
+	})
+
+	Context("When a namespace picks its default class and a class restricts its namespaces", func() {
+		It("Should use the namespace default and reject namespaces the class does not select", func() {
+			newPortalExpose := func(name, namespace, tunnelClassName string) *portalv1alpha1.PortalExpose {
+				return &portalv1alpha1.PortalExpose{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+					Spec: portalv1alpha1.PortalExposeSpec{
+						TunnelClassName: tunnelClassName,
+						App: portalv1alpha1.AppSpec{
+							Name:    name,
+							Service: portalv1alpha1.ServiceRef{Name: "tenant-service", Port: intstr.FromInt32(80)},
+						},
+						Relay: portalv1alpha1.RelaySpec{
+							Targets: []portalv1alpha1.RelayTarget{{Name: "test-relay", URL: connectedRelayURL}},
+						},
+					},
+				}
+			}
+
+			By("Creating a class restricted to tenant namespaces and a tenant namespace picking it")
+			tunnelClass := &portalv1alpha1.TunnelClass{
+				ObjectMeta: metav1.ObjectMeta{Name: "tenant-tunnel-class"},
+				Spec: portalv1alpha1.TunnelClassSpec{
+					Replicas:          2,
+					Size:              "small",
+					AllowedNamespaces: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, tunnelClass)).Should(Succeed())
+
+			tenantNamespace := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "tenant-a",
+					Labels:      map[string]string{"tenant": "a"},
+					Annotations: map[string]string{"portal.gosuda.org/default-tunnel-class": tunnelClass.Name},
+				},
+			}
+			Expect(k8sClient.Create(ctx, tenantNamespace)).Should(Succeed())
+
+			var services []*corev1.Service
+			for _, namespace := range []string{tenantNamespace.Name, "default"} {
+				service := &corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "tenant-service", Namespace: namespace},
+					Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+				}
+				Expect(k8sClient.Create(ctx, service)).Should(Succeed())
+				services = append(services, service)
+			}
+
+			By("Creating a PortalExpose without a class in the tenant namespace")
+			tenantExpose := newPortalExpose("tenant-app", tenantNamespace.Name, "")
+			Expect(k8sClient.Create(ctx, tenantExpose)).Should(Succeed())
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: "tenant-app-tunnel", Namespace: tenantNamespace.Name},
+					&appsv1.Deployment{})
+			}, timeout, interval).Should(Succeed())
+			Eventually(func() int32 {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(tunnelClass), tunnelClass); err != nil {
+					return -1
+				}
+				return tunnelClass.Status.ExposureCount
+			}, timeout, interval).Should(Equal(int32(1)))
+
+			By("Creating a PortalExpose naming the class outside the tenant namespaces")
+			otherExpose := newPortalExpose("other-app", "default", tunnelClass.Name)
+			Expect(k8sClient.Create(ctx, otherExpose)).Should(Succeed())
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(otherExpose), otherExpose); err != nil {
+					return ""
+				}
+				condition := util.FindCondition(otherExpose.Status.Conditions, "TunnelClassExists")
+				if condition == nil {
+					return ""
+				}
+				return condition.Reason
+			}, timeout, interval).Should(Equal("NamespaceNotAllowed"))
+			Expect(otherExpose.Status.Phase).To(Equal(util.PhaseFailed))
+
+			Expect(k8sClient.Delete(ctx, tenantExpose)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, otherExpose)).Should(Succeed())
+			for _, service := range services {
+				Expect(k8sClient.Delete(ctx, service)).Should(Succeed())
+			}
+			deleteTunnelClass(tunnelClass)
+		})
+	})
+})